@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	separatorSyncOption = "="
+)
+
+type rawApplicationTemplate struct {
+	Spec struct {
+		SyncPolicy json.RawMessage `json:"syncPolicy"`
+	} `json:"spec"`
+}
+
+// applySyncPolicyDefaults deep merges the project SyncPolicy into the one of
+// every application and application set template of argocdProject, decoded
+// from data. The templates are merged as written in data, since booleans
+// disabled there, such as automated.prune, are lost once decoded, and a null
+// automated opts out of automated sync.
+func applySyncPolicyDefaults(argocdProject *ArgoCDProject, data []byte) error {
+	defaults := argocdProject.Spec.SyncPolicy
+	if defaults == nil {
+		return nil
+	}
+
+	var raw struct {
+		Spec struct {
+			ApplicationTemplates    []rawApplicationTemplate `json:"applicationTemplates"`
+			ApplicationSetTemplates []struct {
+				Template rawApplicationTemplate `json:"template"`
+			} `json:"applicationSetTemplates"`
+		} `json:"spec"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	spec := &argocdProject.Spec
+	for i := range spec.ApplicationTemplates {
+		if err := mergeSyncPolicy(defaults, &spec.ApplicationTemplates[i].Spec, raw.Spec.ApplicationTemplates[i].Spec.SyncPolicy); err != nil {
+			return err
+		}
+	}
+	for i := range spec.ApplicationSetTemplates {
+		if err := mergeSyncPolicy(defaults, &spec.ApplicationSetTemplates[i].Template.Spec, raw.Spec.ApplicationSetTemplates[i].Template.Spec.SyncPolicy); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func mergeSyncPolicy(defaults *argov1alpha1.SyncPolicy, spec *argov1alpha1.ApplicationSpec, rawSyncPolicy json.RawMessage) error {
+	var overrides interface{} = spec.SyncPolicy
+	if rawSyncPolicy != nil {
+		overrides = rawSyncPolicy
+	}
+
+	var syncPolicy argov1alpha1.SyncPolicy
+	if err := mergeJSON(defaults, overrides, &syncPolicy); err != nil {
+		return err
+	}
+
+	var syncOptions argov1alpha1.SyncOptions
+	if spec.SyncPolicy != nil {
+		syncOptions = spec.SyncPolicy.SyncOptions
+	}
+	syncPolicy.SyncOptions = mergeSyncOptions(defaults.SyncOptions, syncOptions)
+
+	spec.SyncPolicy = &syncPolicy
+	return nil
+}
+
+func applyApplicationDefaults(argocdProject *ArgoCDProject, app *argov1alpha1.Application) {
+	app.Finalizers = mergeFinalizers(argocdProject.Spec.Finalizers, app.Finalizers)
+}
+
+// mergeJSON deep merges the JSON representations of defaults and overrides
+// into out. Objects are merged recursively, while any other value set in
+// overrides replaces the one from defaults.
+func mergeJSON(defaults interface{}, overrides interface{}, out interface{}) error {
+	defaultsMap, err := toJSONMap(defaults)
+	if err != nil {
+		return err
+	}
+
+	overridesMap, err := toJSONMap(overrides)
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(mergeJSONMaps(defaultsMap, overridesMap))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, out)
+}
+
+func mergeJSONMaps(defaults map[string]interface{}, overrides map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(defaults)+len(overrides))
+	for key, value := range defaults {
+		merged[key] = value
+	}
+
+	for key, value := range overrides {
+		defaultsValue, defaultsOk := merged[key].(map[string]interface{})
+		overridesValue, overridesOk := value.(map[string]interface{})
+		if defaultsOk && overridesOk {
+			merged[key] = mergeJSONMaps(defaultsValue, overridesValue)
+		} else {
+			merged[key] = value
+		}
+	}
+
+	return merged
+}
+
+func toJSONMap(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var vm map[string]interface{}
+	if err := json.Unmarshal(b, &vm); err != nil {
+		return nil, err
+	}
+
+	return vm, nil
+}
+
+func mergeSyncOptions(defaults argov1alpha1.SyncOptions, overrides argov1alpha1.SyncOptions) argov1alpha1.SyncOptions {
+	syncOptionKey := func(syncOption string) string {
+		return strings.SplitN(syncOption, separatorSyncOption, 2)[0]
+	}
+
+	overridden := make(map[string]bool, len(overrides))
+	for _, syncOption := range overrides {
+		overridden[syncOptionKey(syncOption)] = true
+	}
+
+	var merged argov1alpha1.SyncOptions
+	for _, syncOption := range defaults {
+		if !overridden[syncOptionKey(syncOption)] {
+			merged = append(merged, syncOption)
+		}
+	}
+	return append(merged, overrides...)
+}
+
+func mergeFinalizers(defaults []string, overrides []string) []string {
+	seen := make(map[string]bool, len(overrides))
+	for _, finalizer := range overrides {
+		seen[finalizer] = true
+	}
+
+	merged := overrides
+	for _, finalizer := range defaults {
+		if !seen[finalizer] {
+			seen[finalizer] = true
+			merged = append(merged, finalizer)
+		}
+	}
+	return merged
+}
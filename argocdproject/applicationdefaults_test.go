@@ -0,0 +1,174 @@
+package main_test
+
+import (
+	"bytes"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject application defaults", func() {
+	factor := int64(2)
+
+	argoCDProject := newArgoCDProject(main.ProjectSpec{
+		SyncPolicy: &argov1alpha1.SyncPolicy{
+			Automated: &argov1alpha1.SyncPolicyAutomated{
+				Prune:    true,
+				SelfHeal: true,
+			},
+			SyncOptions: argov1alpha1.SyncOptions{
+				"CreateNamespace=true",
+				"PruneLast=true",
+			},
+			Retry: &argov1alpha1.RetryStrategy{
+				Limit: 5,
+				Backoff: &argov1alpha1.Backoff{
+					Duration:    "5s",
+					Factor:      &factor,
+					MaxDuration: "3m",
+				},
+			},
+		},
+		Finalizers: []string{
+			"resources-finalizer.argocd.argoproj.io",
+		},
+		ApplicationTemplates: []main.ApplicationTemplate{
+			newApplicationTemplate("github-checker-app", argov1alpha1.ApplicationDestination{}),
+			main.ApplicationTemplate{
+				Application: argov1alpha1.Application{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "another-checker-app",
+						Finalizers: []string{
+							"example.com/finalizer",
+							"resources-finalizer.argocd.argoproj.io",
+						},
+					},
+					Spec: argov1alpha1.ApplicationSpec{
+						Source: &argov1alpha1.ApplicationSource{
+							RepoURL: "https://github.com/t0rr3sp3dr0/another-checker.git",
+						},
+						SyncPolicy: &argov1alpha1.SyncPolicy{
+							SyncOptions: argov1alpha1.SyncOptions{
+								"CreateNamespace=false",
+								"ServerSideApply=true",
+							},
+							Retry: &argov1alpha1.RetryStrategy{
+								Backoff: &argov1alpha1.Backoff{
+									MaxDuration: "10m",
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	ginkgo.It("applies the project defaults to applications without overrides", func() {
+		apps := generateApplications(argoCDProject)
+		g.Expect(apps).To(g.HaveLen(2))
+
+		g.Expect(apps[0].Finalizers).To(g.Equal(argoCDProject.Spec.Finalizers))
+		g.Expect(apps[0].Spec.SyncPolicy).To(g.Equal(argoCDProject.Spec.SyncPolicy))
+	})
+
+	ginkgo.It("lets application fields win over the project defaults", func() {
+		apps := generateApplications(argoCDProject)
+		g.Expect(apps).To(g.HaveLen(2))
+
+		g.Expect(apps[1].Finalizers).To(g.Equal([]string{
+			"example.com/finalizer",
+			"resources-finalizer.argocd.argoproj.io",
+		}))
+		g.Expect(apps[1].Spec.SyncPolicy).To(g.Equal(&argov1alpha1.SyncPolicy{
+			Automated: &argov1alpha1.SyncPolicyAutomated{
+				Prune:    true,
+				SelfHeal: true,
+			},
+			SyncOptions: argov1alpha1.SyncOptions{
+				"PruneLast=true",
+				"CreateNamespace=false",
+				"ServerSideApply=true",
+			},
+			Retry: &argov1alpha1.RetryStrategy{
+				Limit: 5,
+				Backoff: &argov1alpha1.Backoff{
+					Duration:    "5s",
+					Factor:      &factor,
+					MaxDuration: "10m",
+				},
+			},
+		}))
+	})
+
+	ginkgo.It("lets applications disable automated sync options and automated sync in every environment", func() {
+		data := []byte(`
+apiVersion: incognia.com/v1alpha1
+kind: ArgoCDProject
+metadata:
+  name: github-checker
+spec:
+  syncPolicy:
+    automated:
+      prune: true
+      selfHeal: true
+      allowEmpty: true
+    syncOptions:
+      - CreateNamespace=true
+  environments:
+    - name: staging
+    - name: production
+  applicationTemplates:
+    - metadata:
+        name: github-checker-app
+      spec:
+        source:
+          repoURL: https://github.com/t0rr3sp3dr0/github-checker.git
+        syncPolicy:
+          automated:
+            prune: false
+            selfHeal: false
+    - metadata:
+        name: another-checker-app
+      spec:
+        source:
+          repoURL: https://github.com/t0rr3sp3dr0/another-checker.git
+        syncPolicy:
+          automated: null
+`)
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.Succeed())
+
+		var apps []argov1alpha1.Application
+		for _, manifest := range separatorYaml.Split(out.String(), -1) {
+			var app argov1alpha1.Application
+			g.Expect(yaml.Unmarshal([]byte(manifest), &app)).To(g.Succeed())
+			if app.Kind == "Application" {
+				apps = append(apps, app)
+			}
+		}
+		g.Expect(apps).To(g.HaveLen(4))
+
+		for i := 0; i < len(apps); i += 2 {
+			g.Expect(apps[i].Spec.SyncPolicy).To(g.Equal(&argov1alpha1.SyncPolicy{
+				Automated: &argov1alpha1.SyncPolicyAutomated{
+					AllowEmpty: true,
+				},
+				SyncOptions: argov1alpha1.SyncOptions{
+					"CreateNamespace=true",
+				},
+			}))
+			g.Expect(apps[i+1].Spec.SyncPolicy).To(g.Equal(&argov1alpha1.SyncPolicy{
+				SyncOptions: argov1alpha1.SyncOptions{
+					"CreateNamespace=true",
+				},
+			}))
+		}
+	})
+})
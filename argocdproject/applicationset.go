@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application"
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	applicationSetParamID        = "id"
+	applicationSetParamServer    = "server"
+	applicationSetParamCluster   = "cluster"
+	applicationSetParamName      = "name"
+	applicationSetParamNamespace = "namespace"
+
+	applicationSetAnyServer = "*"
+)
+
+type ApplicationSetTemplate struct {
+	Template     ApplicationTemplate         `json:"template,omitempty"`
+	Destinations []ApplicationSetDestination `json:"destinations,omitempty"`
+	Clusters     *metav1.LabelSelector       `json:"clusters,omitempty"`
+}
+
+type ApplicationSetDestination struct {
+	ID          string                              `json:"id"`
+	Destination argov1alpha1.ApplicationDestination `json:"destination,omitempty"`
+}
+
+// destinations returns the destinations the generated Applications may be
+// deployed to. Clusters matched by a label selector are only known by Argo CD,
+// so any server is allowed for the template namespace.
+func (t *ApplicationSetTemplate) destinations() []argov1alpha1.ApplicationDestination {
+	if t.Clusters != nil {
+		return []argov1alpha1.ApplicationDestination{
+			argov1alpha1.ApplicationDestination{
+				Server:    applicationSetAnyServer,
+				Namespace: t.Template.Spec.Destination.Namespace,
+			},
+		}
+	}
+
+	destinations := make([]argov1alpha1.ApplicationDestination, 0, len(t.Destinations))
+	for _, destination := range t.Destinations {
+		destinations = append(destinations, t.resolveDestination(destination))
+	}
+	return destinations
+}
+
+func (t *ApplicationSetTemplate) resolveDestination(destination ApplicationSetDestination) argov1alpha1.ApplicationDestination {
+	resolved := destination.Destination
+	if resolved.Server == "" && resolved.Name == "" {
+		resolved.Server = t.Template.Spec.Destination.Server
+		resolved.Name = t.Template.Spec.Destination.Name
+	}
+	if resolved.Namespace == "" {
+		resolved.Namespace = t.Template.Spec.Destination.Namespace
+	}
+	return resolved
+}
+
+func makeApplicationSets(argocdProject *ArgoCDProject) ([][]byte, error) {
+	appSetTemplates := argocdProject.Spec.ApplicationSetTemplates
+	manifests := make([][]byte, 0, len(appSetTemplates))
+
+	conventions, err := parseConventionTemplates(argocdProject.Spec.Conventions)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range appSetTemplates {
+		appSetTemplate := &appSetTemplates[i]
+		app := &appSetTemplate.Template.Application
+
+		if len(appSetTemplate.Template.DependsOn) != 0 {
+			return nil, fmt.Errorf("application set %q cannot depend on other applications", app.Name)
+		}
+
+		if err := prepareApplication(argocdProject, conventions, &appSetTemplate.Template); err != nil {
+			return nil, err
+		}
+
+		generator, err := makeApplicationSetGenerator(appSetTemplate)
+		if err != nil {
+			return nil, err
+		}
+
+		appSet := argov1alpha1.ApplicationSet{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: argov1alpha1.SchemeGroupVersion.String(),
+				Kind:       application.ApplicationSetKind,
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      app.Name,
+				Namespace: app.Namespace,
+			},
+			Spec: argov1alpha1.ApplicationSetSpec{
+				Generators: []argov1alpha1.ApplicationSetGenerator{
+					*generator,
+				},
+				Template: argov1alpha1.ApplicationSetTemplate{
+					ApplicationSetTemplateMeta: argov1alpha1.ApplicationSetTemplateMeta{
+						Namespace:   app.Namespace,
+						Labels:      app.Labels,
+						Annotations: app.Annotations,
+						Finalizers:  app.Finalizers,
+					},
+					Spec: app.Spec,
+				},
+			},
+		}
+
+		propagateMetadata(argocdProject, &appSet.ObjectMeta)
+		propagateTemplateMetadata(argocdProject, &appSet.Spec.Template.ApplicationSetTemplateMeta)
+
+		destination := &appSet.Spec.Template.Spec.Destination
+		if appSetTemplate.Clusters != nil {
+			appSet.Spec.Template.Name = fmt.Sprintf("%s-{{%s}}", app.Name, applicationSetParamName)
+			destination.Server = fmt.Sprintf("{{%s}}", applicationSetParamServer)
+			destination.Name = ""
+		} else {
+			appSet.Spec.Template.Name = fmt.Sprintf("%s-{{%s}}", app.Name, applicationSetParamID)
+			destination.Server = fmt.Sprintf("{{%s}}", applicationSetParamServer)
+			destination.Name = fmt.Sprintf("{{%s}}", applicationSetParamCluster)
+			destination.Namespace = fmt.Sprintf("{{%s}}", applicationSetParamNamespace)
+		}
+
+		b, err := marshalYAMLWithoutStatusField(appSet)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, b)
+	}
+
+	return manifests, nil
+}
+
+func makeApplicationSetGenerator(appSetTemplate *ApplicationSetTemplate) (*argov1alpha1.ApplicationSetGenerator, error) {
+	name := appSetTemplate.Template.Name
+
+	if appSetTemplate.Clusters != nil {
+		if len(appSetTemplate.Destinations) != 0 {
+			return nil, fmt.Errorf("application set %q cannot define both destinations and clusters", name)
+		}
+
+		return &argov1alpha1.ApplicationSetGenerator{
+			Clusters: &argov1alpha1.ClusterGenerator{
+				Selector: *appSetTemplate.Clusters,
+			},
+		}, nil
+	}
+
+	if len(appSetTemplate.Destinations) == 0 {
+		return nil, fmt.Errorf("application set %q must define either destinations or clusters", name)
+	}
+
+	ids := make(map[string]bool)
+	elements := make([]apiextensionsv1.JSON, 0, len(appSetTemplate.Destinations))
+	for _, destination := range appSetTemplate.Destinations {
+		if destination.ID == "" {
+			return nil, fmt.Errorf("application set %q has a destination without id", name)
+		}
+		if ids[destination.ID] {
+			return nil, fmt.Errorf("application set %q has duplicate destination id %q", name, destination.ID)
+		}
+		ids[destination.ID] = true
+
+		resolved := appSetTemplate.resolveDestination(destination)
+		b, err := json.Marshal(map[string]string{
+			applicationSetParamID:        destination.ID,
+			applicationSetParamServer:    resolved.Server,
+			applicationSetParamCluster:   resolved.Name,
+			applicationSetParamNamespace: resolved.Namespace,
+		})
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, apiextensionsv1.JSON{Raw: b})
+	}
+
+	return &argov1alpha1.ApplicationSetGenerator{
+		List: &argov1alpha1.ListGenerator{
+			Elements: elements,
+		},
+	}, nil
+}
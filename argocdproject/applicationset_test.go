@@ -0,0 +1,198 @@
+package main_test
+
+import (
+	"bytes"
+	"encoding/json"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject application sets", func() {
+	appTemplate := newApplicationTemplate("github-checker", argov1alpha1.ApplicationDestination{
+		Namespace: "github-checker",
+	})
+
+	ginkgo.It("generates a list generator from destinations", func() {
+		project := newArgoCDProject(main.ProjectSpec{
+			Environment: "production",
+			ApplicationSetTemplates: []main.ApplicationSetTemplate{
+				main.ApplicationSetTemplate{
+					Template: appTemplate,
+					Destinations: []main.ApplicationSetDestination{
+						main.ApplicationSetDestination{
+							ID: "sre",
+							Destination: argov1alpha1.ApplicationDestination{
+								Name: "Global-SRE",
+							},
+						},
+						main.ApplicationSetDestination{
+							ID: "product",
+							Destination: argov1alpha1.ApplicationDestination{
+								Name:      "Global-Product",
+								Namespace: "another-checker",
+							},
+						},
+					},
+				},
+			},
+		})
+
+		appSets := generateApplicationSets(project)
+		g.Expect(appSets).To(g.HaveLen(1))
+		g.Expect(generateApplications(project)).To(g.BeEmpty())
+
+		appSet := appSets[0]
+		g.Expect(appSet.Name).To(g.Equal("github-checker"))
+		g.Expect(appSet.Spec.Generators).To(g.HaveLen(1))
+		g.Expect(appSet.Spec.Generators[0].List).NotTo(g.BeNil())
+
+		var elements []map[string]string
+		for _, element := range appSet.Spec.Generators[0].List.Elements {
+			var params map[string]string
+			g.Expect(json.Unmarshal(element.Raw, &params)).To(g.Succeed())
+			elements = append(elements, params)
+		}
+		g.Expect(elements).To(g.Equal([]map[string]string{
+			{"id": "sre", "server": "", "cluster": "Global-SRE", "namespace": "github-checker"},
+			{"id": "product", "server": "", "cluster": "Global-Product", "namespace": "another-checker"},
+		}))
+
+		template := appSet.Spec.Template
+		g.Expect(template.Name).To(g.Equal("github-checker-{{id}}"))
+		g.Expect(template.Spec.Project).To(g.Equal("github-checker"))
+		g.Expect(template.Spec.Source.Path).To(g.Equal("./k8s/overlays/production"))
+		g.Expect(template.Spec.Source.TargetRevision).To(g.Equal("env-production"))
+		g.Expect(template.Spec.Destination).To(g.Equal(argov1alpha1.ApplicationDestination{
+			Server:    "{{server}}",
+			Name:      "{{cluster}}",
+			Namespace: "{{namespace}}",
+		}))
+
+		appProject := generateAppProject(project)
+		g.Expect(appProject.Spec.Destinations).To(g.Equal([]argov1alpha1.ApplicationDestination{
+			{Name: "Global-Product", Namespace: "another-checker"},
+			{Name: "Global-SRE", Namespace: "github-checker"},
+		}))
+		g.Expect(appProject.Spec.SourceRepos).To(g.Equal([]string{
+			"https://github.com/t0rr3sp3dr0/github-checker.git",
+		}))
+	})
+
+	ginkgo.It("generates a cluster generator from a label selector", func() {
+		selector := metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"environment": "production",
+			},
+		}
+		project := newArgoCDProject(main.ProjectSpec{
+			Environment: "production",
+			ApplicationSetTemplates: []main.ApplicationSetTemplate{
+				main.ApplicationSetTemplate{
+					Template: appTemplate,
+					Clusters: &selector,
+				},
+			},
+		})
+
+		appSets := generateApplicationSets(project)
+		g.Expect(appSets).To(g.HaveLen(1))
+
+		appSet := appSets[0]
+		g.Expect(appSet.Spec.Generators).To(g.HaveLen(1))
+		g.Expect(appSet.Spec.Generators[0].Clusters).NotTo(g.BeNil())
+		g.Expect(appSet.Spec.Generators[0].Clusters.Selector).To(g.Equal(selector))
+
+		template := appSet.Spec.Template
+		g.Expect(template.Name).To(g.Equal("github-checker-{{name}}"))
+		g.Expect(template.Spec.Destination).To(g.Equal(argov1alpha1.ApplicationDestination{
+			Server:    "{{server}}",
+			Namespace: "github-checker",
+		}))
+
+		appProject := generateAppProject(project)
+		g.Expect(appProject.Spec.Destinations).To(g.Equal([]argov1alpha1.ApplicationDestination{
+			{Server: "*", Namespace: "github-checker"},
+		}))
+	})
+
+	ginkgo.It("keeps explicit project destinations when selecting clusters", func() {
+		destinations := []argov1alpha1.ApplicationDestination{
+			argov1alpha1.ApplicationDestination{
+				Name:      "Global-Production",
+				Namespace: "github-checker",
+			},
+		}
+
+		project := newArgoCDProject(main.ProjectSpec{
+			Environment: "production",
+			AppProject: argov1alpha1.AppProject{
+				Spec: argov1alpha1.AppProjectSpec{
+					Destinations: destinations,
+				},
+			},
+			ApplicationSetTemplates: []main.ApplicationSetTemplate{
+				main.ApplicationSetTemplate{
+					Template: appTemplate,
+					Clusters: &metav1.LabelSelector{},
+				},
+			},
+		})
+
+		appProject := generateAppProject(project)
+		g.Expect(appProject.Spec.Destinations).To(g.Equal(destinations))
+	})
+
+	ginkgo.DescribeTable("rejects", func(appSetTemplate main.ApplicationSetTemplate, message string) {
+		appSetTemplate.Template = appTemplate
+		data, err := yaml.Marshal(newArgoCDProject(main.ProjectSpec{
+			Environment: "production",
+			ApplicationSetTemplates: []main.ApplicationSetTemplate{
+				appSetTemplate,
+			},
+		}))
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(g.ContainSubstring(message)))
+	},
+		ginkgo.Entry("template without destinations nor clusters", main.ApplicationSetTemplate{}, "must define either destinations or clusters"),
+		ginkgo.Entry("template with destinations and clusters", main.ApplicationSetTemplate{
+			Destinations: []main.ApplicationSetDestination{
+				{ID: "sre"},
+			},
+			Clusters: &metav1.LabelSelector{},
+		}, "cannot define both destinations and clusters"),
+		ginkgo.Entry("destination without id", main.ApplicationSetTemplate{
+			Destinations: []main.ApplicationSetDestination{
+				{},
+			},
+		}, "has a destination without id"),
+		ginkgo.Entry("duplicate destination id", main.ApplicationSetTemplate{
+			Destinations: []main.ApplicationSetDestination{
+				{ID: "sre"},
+				{ID: "sre"},
+			},
+		}, `duplicate destination id "sre"`),
+	)
+})
+
+func generateApplicationSets(argoCDProject main.ArgoCDProject) []argov1alpha1.ApplicationSet {
+	var appSets []argov1alpha1.ApplicationSet
+	for _, manifest := range generateManifests(argoCDProject) {
+		var meta metav1.TypeMeta
+		g.Expect(yaml.Unmarshal([]byte(manifest), &meta)).To(g.Succeed())
+
+		if meta.GroupVersionKind() == argov1alpha1.ApplicationSetSchemaGroupVersionKind {
+			var appSet argov1alpha1.ApplicationSet
+			g.Expect(yaml.Unmarshal([]byte(manifest), &appSet)).To(g.Succeed())
+			appSets = append(appSets, appSet)
+		}
+	}
+	return appSets
+}
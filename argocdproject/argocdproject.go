@@ -1,11 +1,13 @@
 package main
 
 import (
-	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
+	"sort"
+	"strings"
+	"text/template"
 
 	"github.com/argoproj/argo-cd/v2/pkg/apis/application"
 	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
@@ -19,6 +21,13 @@ const (
 
 	stagingEnvironment = "staging"
 
+	sourceRepoAll = "*"
+
+	defaultPathConvention           = "./k8s/overlays/{{ .Environment }}"
+	defaultTargetRevisionConvention = "env-{{ .Environment }}"
+
+	tmplOption = "missingkey=error"
+
 	yamlStatusField = "status"
 )
 
@@ -40,7 +49,7 @@ func (a accessLevel) String() string {
 	}
 }
 
-func (a accessLevel) Policies(appProjectName string, environment string) []string {
+func (a accessLevel) Policies(appProjectName string) []string {
 	switch a {
 	case ReadOnly:
 		return []string{
@@ -48,7 +57,7 @@ func (a accessLevel) Policies(appProjectName string, environment string) []strin
 		}
 
 	case ReadSync:
-		defaultPolicies := []string{
+		return []string{
 			fmt.Sprintf("p, proj:%[1]s:%[2]s, applications, action/apps/Deployment/restart, %[1]s/*, allow", appProjectName, ReadSync),
 			fmt.Sprintf("p, proj:%[1]s:%[2]s, applications, action/argoproj.io/Rollout/abort, %[1]s/*, allow", appProjectName, ReadSync),
 			fmt.Sprintf("p, proj:%[1]s:%[2]s, applications, action/argoproj.io/Rollout/promote-full, %[1]s/*, allow", appProjectName, ReadSync),
@@ -58,32 +67,129 @@ func (a accessLevel) Policies(appProjectName string, environment string) []strin
 			fmt.Sprintf("p, proj:%[1]s:%[2]s, applications, sync, %[1]s/*, allow", appProjectName, ReadSync),
 			fmt.Sprintf("g, proj:%[1]s:%[2]s, proj:%[1]s:%[3]s", appProjectName, ReadSync, ReadOnly),
 		}
-		if environment == stagingEnvironment {
-			defaultPolicies = append(defaultPolicies, fmt.Sprintf("p, proj:%[1]s:%[2]s, applications, override, %[1]s/*, allow", appProjectName, ReadSync))
-		}
-		return defaultPolicies
 
 	default:
 		panic(fmt.Sprintf("unknown access level %d", a))
 	}
 }
 
+type AccessControlRole struct {
+	Name     string   `json:"name"`
+	Groups   []string `json:"groups,omitempty"`
+	Actions  []string `json:"actions,omitempty"`
+	Inherits []string `json:"inherits,omitempty"`
+}
+
+func (r AccessControlRole) Policies(appProjectName string) []string {
+	policies := make([]string, 0, len(r.Actions)+len(r.Inherits))
+	for _, action := range r.Actions {
+		policies = append(policies, fmt.Sprintf("p, proj:%[1]s:%[2]s, applications, %[3]s, %[1]s/*, allow", appProjectName, r.Name, action))
+	}
+	for _, inherited := range r.Inherits {
+		policies = append(policies, fmt.Sprintf("g, proj:%[1]s:%[2]s, proj:%[1]s:%[3]s", appProjectName, r.Name, inherited))
+	}
+	return policies
+}
+
 type ArgoCDProject struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 	Spec              ProjectSpec `json:"spec,omitempty"`
+
+	nameSuffix string
 }
 
 type ProjectSpec struct {
-	AccessControl        AppProjectAccessControl    `json:"accessControl,omitempty"`
-	Environment          string                     `json:"environment,omitempty"`
-	AppProject           argov1alpha1.AppProject    `json:"appProjectTemplate,omitempty"`
-	ApplicationTemplates []argov1alpha1.Application `json:"applicationTemplates,omitempty"`
+	AccessControl           AppProjectAccessControl                        `json:"accessControl,omitempty"`
+	Environment             string                                         `json:"environment,omitempty"`
+	Environments            []ProjectEnvironment                           `json:"environments,omitempty"`
+	AllowAnySourceRepo      bool                                           `json:"allowAnySourceRepo,omitempty"`
+	Conventions             ProjectConventions                             `json:"conventions,omitempty"`
+	EnvironmentProfiles     []EnvironmentProfile                           `json:"environmentProfiles,omitempty"`
+	EnvironmentProfilesFile string                                         `json:"environmentProfilesFile,omitempty"`
+	SyncPolicy              *argov1alpha1.SyncPolicy                       `json:"syncPolicy,omitempty"`
+	Finalizers              []string                                       `json:"finalizers,omitempty"`
+	MaintenanceWindows      []MaintenanceWindow                            `json:"maintenanceWindows,omitempty"`
+	EmitNamespaces          bool                                           `json:"emitNamespaces,omitempty"`
+	EmitRBACPolicy          bool                                           `json:"emitRBACPolicy,omitempty"`
+	ResourcePresets         []string                                       `json:"resourcePresets,omitempty"`
+	OrphanedResources       *argov1alpha1.OrphanedResourcesMonitorSettings `json:"orphanedResources,omitempty"`
+	SignatureKeys           []string                                       `json:"signatureKeys,omitempty"`
+	RootApplication         *RootApplication                               `json:"rootApplication,omitempty"`
+	PropagateMetadata       MetadataPropagation                            `json:"propagateMetadata,omitempty"`
+	Notifications           []NotificationSubscription                     `json:"notifications,omitempty"`
+	AppProject              argov1alpha1.AppProject                        `json:"appProjectTemplate,omitempty"`
+	ApplicationTemplates    []ApplicationTemplate                          `json:"applicationTemplates,omitempty"`
+	ApplicationSetTemplates []ApplicationSetTemplate                       `json:"applicationSetTemplates,omitempty"`
+}
+
+type ApplicationTemplate struct {
+	argov1alpha1.Application `json:",inline"`
+	ImageUpdater             *ImageUpdater       `json:"imageUpdater,omitempty"`
+	DependsOn                []string            `json:"dependsOn,omitempty"`
+	Helm                     *HelmShorthand      `json:"helm,omitempty"`
+	Kustomize                *KustomizeShorthand `json:"kustomize,omitempty"`
 }
 
 type AppProjectAccessControl struct {
-	ReadOnly []string `json:"ReadOnly,omitempty"`
-	ReadSync []string `json:"ReadSync,omitempty"`
+	ReadOnly []string            `json:"ReadOnly,omitempty"`
+	ReadSync []string            `json:"ReadSync,omitempty"`
+	Roles    []AccessControlRole `json:"roles,omitempty"`
+	CI       []CIRole            `json:"ci,omitempty"`
+	Global   []GlobalPolicy      `json:"global,omitempty"`
+}
+
+type ProjectConventions struct {
+	Path           string `json:"path,omitempty"`
+	TargetRevision string `json:"targetRevision,omitempty"`
+}
+
+type conventionData struct {
+	Project     string
+	Application string
+	Environment string
+}
+
+type conventionTemplates struct {
+	path           *template.Template
+	targetRevision *template.Template
+}
+
+func parseConventionTemplates(conventions ProjectConventions) (*conventionTemplates, error) {
+	path, err := parseConventionTemplate("path", conventions.Path, defaultPathConvention)
+	if err != nil {
+		return nil, err
+	}
+
+	targetRevision, err := parseConventionTemplate("targetRevision", conventions.TargetRevision, defaultTargetRevisionConvention)
+	if err != nil {
+		return nil, err
+	}
+
+	return &conventionTemplates{
+		path:           path,
+		targetRevision: targetRevision,
+	}, nil
+}
+
+func parseConventionTemplate(name string, text string, defaultText string) (*template.Template, error) {
+	if text == "" {
+		text = defaultText
+	}
+
+	tmpl, err := template.New(name).Option(tmplOption).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s convention: %w", name, err)
+	}
+	return tmpl, nil
+}
+
+func executeConventionTemplate(tmpl *template.Template, data conventionData) (string, error) {
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("application %q: %w", data.Application, err)
+	}
+	return sb.String(), nil
 }
 
 func main() {
@@ -105,6 +211,10 @@ func GenerateManifests(data []byte, out io.Writer) error {
 		return err
 	}
 
+	if err := applySyncPolicyDefaults(&argocdProject, data); err != nil {
+		return err
+	}
+
 	manifests, err := makeManifests(&argocdProject)
 	if err != nil {
 		return err
@@ -126,6 +236,55 @@ func GenerateManifests(data []byte, out io.Writer) error {
 func makeManifests(argocdProject *ArgoCDProject) ([][]byte, error) {
 	var manifests [][]byte
 
+	if err := validateProjectSpec(&argocdProject.Spec); err != nil {
+		return nil, err
+	}
+
+	environmentProjects, err := expandEnvironments(argocdProject)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceEnvironments := make(map[string]string)
+	for _, environmentProject := range environmentProjects {
+		if environmentProject.Spec.EmitNamespaces {
+			for _, namespace := range destinationNamespaces(environmentProject) {
+				if other, ok := namespaceEnvironments[namespace]; ok {
+					return nil, fmt.Errorf("namespace %q is emitted by environments %q and %q", namespace, other, environmentProject.Spec.Environment)
+				}
+				namespaceEnvironments[namespace] = environmentProject.Spec.Environment
+			}
+		}
+
+		bs, err := makeProjectManifests(environmentProject)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, bs...)
+	}
+
+	b, err := makeRBACConfigMap(argocdProject, environmentProjects)
+	if err != nil {
+		return nil, err
+	}
+	if b != nil {
+		manifests = append(manifests, b)
+	}
+
+	b, err = makeRootApplication(argocdProject)
+	if err != nil {
+		return nil, err
+	}
+	if b != nil {
+		manifests = append(manifests, b)
+	}
+
+	return manifests, nil
+}
+
+func makeProjectManifests(argocdProject *ArgoCDProject) ([][]byte, error) {
+	var manifests [][]byte
+
 	b, err := makeAppProject(argocdProject)
 	if err != nil {
 		return nil, err
@@ -138,6 +297,18 @@ func makeManifests(argocdProject *ArgoCDProject) ([][]byte, error) {
 	}
 	manifests = append(manifests, bs...)
 
+	bs, err = makeApplicationSets(argocdProject)
+	if err != nil {
+		return nil, err
+	}
+	manifests = append(manifests, bs...)
+
+	bs, err = makeNamespaces(argocdProject)
+	if err != nil {
+		return nil, err
+	}
+	manifests = append(manifests, bs...)
+
 	return manifests, nil
 }
 
@@ -149,43 +320,149 @@ func makeAppProject(argocdProject *ArgoCDProject) ([]byte, error) {
 		Kind:       application.AppProjectKind,
 	}
 
-	appProject.Name = argocdProject.Name
+	appProject.Name = argocdProject.appProjectName()
 
-	appProject.Spec.NamespaceResourceWhitelist = []metav1.GroupKind{
-		metav1.GroupKind{
-			Group: "*",
-			Kind:  "*",
-		},
-	}
+	propagateMetadata(argocdProject, &appProject.ObjectMeta)
+
+	applyRootApplicationSyncWave(argocdProject, appProject)
 
-	// TODO only allow SourceRepos required by applications to avoid unnecessary permissions
-	appProject.Spec.SourceRepos = []string{
-		"*",
+	if err := applyResourcePresets(argocdProject, &appProject.Spec); err != nil {
+		return nil, err
 	}
 
+	appProject.Spec.SourceRepos = makeSourceRepos(argocdProject, appProject.Spec.SourceRepos)
+
 	if appProject.Spec.Destinations == nil {
 		destinationMap := make(map[string]argov1alpha1.ApplicationDestination)
 		for _, app := range argocdProject.Spec.ApplicationTemplates {
 			destinationMap[app.Spec.Destination.String()] = app.Spec.Destination
 		}
+		for i := range argocdProject.Spec.ApplicationSetTemplates {
+			for _, destination := range argocdProject.Spec.ApplicationSetTemplates[i].destinations() {
+				destinationMap[destination.String()] = destination
+			}
+		}
 
 		destinations := make([]argov1alpha1.ApplicationDestination, 0, len(destinationMap))
 		for _, destination := range destinationMap {
 			destinations = append(destinations, destination)
 		}
+		sortDestinations(destinations)
 		appProject.Spec.Destinations = destinations
 	}
 
-	readOnlyProjectRole := makeProjectRole(ReadOnly, argocdProject, appProject)
+	syncWindows, err := makeSyncWindows(argocdProject)
+	if err != nil {
+		return nil, err
+	}
+	appProject.Spec.SyncWindows = append(appProject.Spec.SyncWindows, syncWindows...)
+
+	environmentProfile, err := resolveEnvironmentProfile(argocdProject)
+	if err != nil {
+		return nil, err
+	}
+
+	builtinRoles := make(map[int]accessLevel)
+
+	readOnlyProjectRole := makeProjectRole(ReadOnly, argocdProject, appProject, environmentProfile)
+	builtinRoles[len(appProject.Spec.Roles)] = ReadOnly
 	appProject.Spec.Roles = append(appProject.Spec.Roles, *readOnlyProjectRole)
 
-	readSyncProjectRole := makeProjectRole(ReadSync, argocdProject, appProject)
+	readSyncProjectRole := makeProjectRole(ReadSync, argocdProject, appProject, environmentProfile)
+	builtinRoles[len(appProject.Spec.Roles)] = ReadSync
 	appProject.Spec.Roles = append(appProject.Spec.Roles, *readSyncProjectRole)
 
+	customProjectRoles, err := makeCustomProjectRoles(argocdProject, appProject, environmentProfile)
+	if err != nil {
+		return nil, err
+	}
+	appProject.Spec.Roles = append(appProject.Spec.Roles, customProjectRoles...)
+
+	ciProjectRoles, err := makeCIProjectRoles(argocdProject, appProject)
+	if err != nil {
+		return nil, err
+	}
+	appProject.Spec.Roles = append(appProject.Spec.Roles, ciProjectRoles...)
+
+	roleNames := map[string]bool{
+		readOnlyProjectRole.Name: true,
+		readSyncProjectRole.Name: true,
+	}
+	for _, customProjectRole := range customProjectRoles {
+		roleNames[customProjectRole.Name] = true
+	}
+	if err := validateEnvironmentProfile(environmentProfile, roleNames); err != nil {
+		return nil, err
+	}
+
+	if err := validateProjectRoles(appProject, builtinRoles); err != nil {
+		return nil, err
+	}
+
+	if err := applyOrphanedResources(argocdProject, environmentProfile, &appProject.Spec); err != nil {
+		return nil, err
+	}
+
+	if err := applySignatureKeys(argocdProject, environmentProfile, &appProject.Spec); err != nil {
+		return nil, err
+	}
+
 	return marshalYAMLWithoutStatusField(appProject)
 }
 
-func makeProjectRole(accessLevel accessLevel, argocdProject *ArgoCDProject, appProject *argov1alpha1.AppProject) *argov1alpha1.ProjectRole {
+func sortDestinations(destinations []argov1alpha1.ApplicationDestination) {
+	sort.Slice(destinations, func(i, j int) bool {
+		destinationI := destinations[i]
+		destinationJ := destinations[j]
+
+		if destinationI.Server != destinationJ.Server {
+			return destinationI.Server < destinationJ.Server
+		}
+		if destinationI.Name != destinationJ.Name {
+			return destinationI.Name < destinationJ.Name
+		}
+		return destinationI.Namespace < destinationJ.Namespace
+	})
+}
+
+func makeSourceRepos(argocdProject *ArgoCDProject, sourceRepos []string) []string {
+	if argocdProject.Spec.AllowAnySourceRepo {
+		return []string{
+			sourceRepoAll,
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, sourceRepo := range sourceRepos {
+		if sourceRepo == sourceRepoAll {
+			return sourceRepos
+		}
+		seen[sourceRepo] = true
+	}
+
+	appSpecs := make([]argov1alpha1.ApplicationSpec, 0, len(argocdProject.Spec.ApplicationTemplates)+len(argocdProject.Spec.ApplicationSetTemplates))
+	for _, app := range argocdProject.Spec.ApplicationTemplates {
+		appSpecs = append(appSpecs, app.Spec)
+	}
+	for _, appSetTemplate := range argocdProject.Spec.ApplicationSetTemplates {
+		appSpecs = append(appSpecs, appSetTemplate.Template.Spec)
+	}
+
+	var repoURLs []string
+	for _, appSpec := range appSpecs {
+		for _, source := range appSpec.GetSources() {
+			if source.RepoURL != "" && !seen[source.RepoURL] {
+				seen[source.RepoURL] = true
+				repoURLs = append(repoURLs, source.RepoURL)
+			}
+		}
+	}
+	sort.Strings(repoURLs)
+
+	return append(sourceRepos, repoURLs...)
+}
+
+func makeProjectRole(accessLevel accessLevel, argocdProject *ArgoCDProject, appProject *argov1alpha1.AppProject, environmentProfile *EnvironmentProfile) *argov1alpha1.ProjectRole {
 	var groups []string
 	switch accessLevel {
 	case ReadOnly:
@@ -196,32 +473,107 @@ func makeProjectRole(accessLevel accessLevel, argocdProject *ArgoCDProject, appP
 
 	return &argov1alpha1.ProjectRole{
 		Name:     accessLevel.String(),
-		Policies: accessLevel.Policies(appProject.Name, argocdProject.Spec.Environment),
+		Policies: append(accessLevel.Policies(appProject.Name), environmentProfile.Policies(appProject.Name, accessLevel.String())...),
 		Groups:   groups,
 	}
 }
 
+func makeCustomProjectRoles(argocdProject *ArgoCDProject, appProject *argov1alpha1.AppProject, environmentProfile *EnvironmentProfile) ([]argov1alpha1.ProjectRole, error) {
+	roles := argocdProject.Spec.AccessControl.Roles
+
+	roleMap := map[string]*AccessControlRole{
+		ReadOnly.String(): nil,
+		ReadSync.String(): nil,
+	}
+	for i := range roles {
+		role := &roles[i]
+
+		if role.Name == "" {
+			return nil, fmt.Errorf("access control role %d has no name", i)
+		}
+		if _, ok := roleMap[role.Name]; ok {
+			return nil, fmt.Errorf("access control role %q is already defined", role.Name)
+		}
+		roleMap[role.Name] = role
+	}
+
+	for _, role := range roles {
+		for _, inherited := range role.Inherits {
+			if _, ok := roleMap[inherited]; !ok {
+				return nil, fmt.Errorf("access control role %q inherits unknown role %q", role.Name, inherited)
+			}
+		}
+	}
+
+	visiting := make(map[string]bool)
+	visited := make(map[string]bool)
+	var visit func(name string) error
+	visit = func(name string) error {
+		if visited[name] {
+			return nil
+		}
+		if visiting[name] {
+			return fmt.Errorf("access control role %q inherits itself", name)
+		}
+		visiting[name] = true
+
+		if role := roleMap[name]; role != nil {
+			for _, inherited := range role.Inherits {
+				if err := visit(inherited); err != nil {
+					return err
+				}
+			}
+		}
+
+		visiting[name] = false
+		visited[name] = true
+		return nil
+	}
+
+	projectRoles := make([]argov1alpha1.ProjectRole, 0, len(roles))
+	for _, role := range roles {
+		if err := visit(role.Name); err != nil {
+			return nil, err
+		}
+
+		projectRoles = append(projectRoles, argov1alpha1.ProjectRole{
+			Name:     role.Name,
+			Policies: append(role.Policies(appProject.Name), environmentProfile.Policies(appProject.Name, role.Name)...),
+			Groups:   role.Groups,
+		})
+	}
+
+	return projectRoles, nil
+}
+
 func makeApplications(argocdProject *ArgoCDProject) ([][]byte, error) {
 	apps := argocdProject.Spec.ApplicationTemplates
 	manifests := make([][]byte, 0, len(apps))
 
+	conventions, err := parseConventionTemplates(argocdProject.Spec.Conventions)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := applyDependencySyncWaves(apps); err != nil {
+		return nil, err
+	}
+
 	for i := range apps {
-		app := &apps[i]
+		appTemplate := &apps[i]
+		app := &appTemplate.Application
 
 		app.TypeMeta = metav1.TypeMeta{
 			APIVersion: argov1alpha1.SchemeGroupVersion.String(),
 			Kind:       application.ApplicationKind,
 		}
 
-		app.Spec.Project = argocdProject.Name
-
-		if argocdProject.Spec.Environment != "" {
-			if app.Spec.Source.Path == "" {
-				app.Spec.Source.Path = fmt.Sprintf("./k8s/overlays/%s", argocdProject.Spec.Environment)
-			}
-			app.Spec.Source.TargetRevision = fmt.Sprintf("env-%s", argocdProject.Spec.Environment)
+		if err := prepareApplication(argocdProject, conventions, appTemplate); err != nil {
+			return nil, err
 		}
 
+		propagateMetadata(argocdProject, &app.ObjectMeta)
+
 		b, err := marshalYAMLWithoutStatusField(app)
 		if err != nil {
 			return nil, err
@@ -232,14 +584,94 @@ func makeApplications(argocdProject *ArgoCDProject) ([][]byte, error) {
 	return manifests, nil
 }
 
-func marshalYAMLWithoutStatusField(v interface{}) ([]byte, error) {
-	b, err := json.Marshal(v)
+func prepareApplication(argocdProject *ArgoCDProject, conventions *conventionTemplates, appTemplate *ApplicationTemplate) error {
+	app := &appTemplate.Application
+	app.Spec.Project = argocdProject.appProjectName()
+
+	applyApplicationDefaults(argocdProject, app)
+
+	if err := applyNotificationSubscriptions(argocdProject, app); err != nil {
+		return err
+	}
+
+	if err := applyImageUpdater(appTemplate.ImageUpdater, app); err != nil {
+		return err
+	}
+
+	data := conventionData{
+		Project:     argocdProject.Name,
+		Application: app.Name,
+		Environment: argocdProject.Spec.Environment,
+	}
+
+	if argocdProject.Spec.Environment != "" {
+		multipleSources := app.Spec.HasMultipleSources()
+		conventionSources := applicationSources(app, func(source *argov1alpha1.ApplicationSource) bool {
+			return !multipleSources || source.Chart == ""
+		})
+		for _, source := range conventionSources {
+			if err := applyConventions(conventions, data, source); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := applySourceShorthands(appTemplate, data); err != nil {
+		return err
+	}
+
+	app.Name += argocdProject.nameSuffix
+
+	return nil
+}
+
+// applicationSources returns the sources of app accepted by filter. Ref
+// sources of multi-source applications only expose their files to the other
+// sources, so they are never returned.
+func applicationSources(app *argov1alpha1.Application, filter func(source *argov1alpha1.ApplicationSource) bool) []*argov1alpha1.ApplicationSource {
+	var candidates []*argov1alpha1.ApplicationSource
+	if !app.Spec.HasMultipleSources() {
+		if app.Spec.Source != nil {
+			candidates = append(candidates, app.Spec.Source)
+		}
+	} else {
+		for i := range app.Spec.Sources {
+			if source := &app.Spec.Sources[i]; source.Ref == "" {
+				candidates = append(candidates, source)
+			}
+		}
+	}
+
+	var sources []*argov1alpha1.ApplicationSource
+	for _, source := range candidates {
+		if filter(source) {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+func applyConventions(conventions *conventionTemplates, data conventionData, source *argov1alpha1.ApplicationSource) error {
+	if source.Path == "" {
+		path, err := executeConventionTemplate(conventions.path, data)
+		if err != nil {
+			return err
+		}
+		source.Path = path
+	}
+
+	targetRevision, err := executeConventionTemplate(conventions.targetRevision, data)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	source.TargetRevision = targetRevision
+
+	return nil
+}
 
-	var vm map[string]interface{}
-	if err := json.Unmarshal(b, &vm); err != nil {
+func marshalYAMLWithoutStatusField(v interface{}) ([]byte, error) {
+	vm, err := toJSONMap(v)
+	if err != nil {
 		return nil, err
 	}
 
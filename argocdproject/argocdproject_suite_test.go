@@ -3,11 +3,44 @@ package main_test
 import (
 	"testing"
 
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
 	"github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
 )
 
 func TestArgoCDProject(t *testing.T) {
 	gomega.RegisterFailHandler(ginkgo.Fail)
 	ginkgo.RunSpecs(t, "ArgoCDProject Suite")
 }
+
+// newArgoCDProject returns the github-checker ArgoCDProject with spec, which
+// the specs of the suite build their cases from.
+func newArgoCDProject(spec main.ProjectSpec) main.ArgoCDProject {
+	return main.ArgoCDProject{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "github-checker",
+		},
+		Spec: spec,
+	}
+}
+
+// newApplicationTemplate returns an application template named name, deploying
+// the github-checker repository to destination.
+func newApplicationTemplate(name string, destination argov1alpha1.ApplicationDestination) main.ApplicationTemplate {
+	return main.ApplicationTemplate{
+		Application: argov1alpha1.Application{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+			},
+			Spec: argov1alpha1.ApplicationSpec{
+				Source: &argov1alpha1.ApplicationSource{
+					RepoURL: "https://github.com/t0rr3sp3dr0/github-checker.git",
+				},
+				Destination: destination,
+			},
+		},
+	}
+}
@@ -44,18 +44,133 @@ var _ = ginkgo.Describe("ArgoCDProject", func() {
 					},
 				},
 				Environment: "staging",
-				ApplicationTemplates: []argov1alpha1.Application{
-					argov1alpha1.Application{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "github-checker-app",
+				ApplicationTemplates: []main.ApplicationTemplate{
+					main.ApplicationTemplate{
+						Application: argov1alpha1.Application{
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "github-checker-app",
+							},
+							Spec: argov1alpha1.ApplicationSpec{
+								Source: &argov1alpha1.ApplicationSource{
+									RepoURL: "https://github.com/t0rr3sp3dr0/github-checker.git",
+								},
+								Destination: argov1alpha1.ApplicationDestination{
+									Name:      "arn:aws:eks:us:123456789876:cluster/Global-SRE",
+									Namespace: "github-checker",
+								},
+							},
 						},
-						Spec: argov1alpha1.ApplicationSpec{
-							Source: &argov1alpha1.ApplicationSource{
-								RepoURL: "https://github.com/t0rr3sp3dr0/github-checker.git",
+					},
+				},
+			},
+		}),
+		ginkgo.Entry("with custom access control roles", main.ArgoCDProject{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: schema.GroupVersion{
+					Group:   "incognia.com",
+					Version: "v1alpha1",
+				}.String(),
+				Kind: "ArgoCDProject",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "github-checker",
+			},
+			Spec: main.ProjectSpec{
+				AccessControl: main.AppProjectAccessControl{
+					ReadOnly: []string{
+						"sre:eng-2",
+					},
+					ReadSync: []string{
+						"sre:eng-0",
+						"sre:eng-1",
+					},
+					Roles: []main.AccessControlRole{
+						main.AccessControlRole{
+							Name: "admin",
+							Groups: []string{
+								"sre:admin",
 							},
-							Destination: argov1alpha1.ApplicationDestination{
-								Name:      "arn:aws:eks:us:123456789876:cluster/Global-SRE",
-								Namespace: "github-checker",
+							Actions: []string{
+								"delete",
+								"update",
+								"action/*",
+							},
+							Inherits: []string{
+								"operator",
+							},
+						},
+						main.AccessControlRole{
+							Name: "operator",
+							Groups: []string{
+								"sre:operator",
+							},
+							Actions: []string{
+								"override",
+							},
+							Inherits: []string{
+								main.ReadSync.String(),
+							},
+						},
+					},
+				},
+				Environment: "staging",
+				ApplicationTemplates: []main.ApplicationTemplate{
+					main.ApplicationTemplate{
+						Application: argov1alpha1.Application{
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "github-checker-app",
+							},
+							Spec: argov1alpha1.ApplicationSpec{
+								Source: &argov1alpha1.ApplicationSource{
+									RepoURL: "https://github.com/t0rr3sp3dr0/github-checker.git",
+								},
+								Destination: argov1alpha1.ApplicationDestination{
+									Name:      "arn:aws:eks:us:123456789876:cluster/Global-SRE",
+									Namespace: "github-checker",
+								},
+							},
+						},
+					},
+				},
+			},
+		}),
+		ginkgo.Entry("with any source repository allowed", main.ArgoCDProject{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: schema.GroupVersion{
+					Group:   "incognia.com",
+					Version: "v1alpha1",
+				}.String(),
+				Kind: "ArgoCDProject",
+			},
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "github-checker",
+			},
+			Spec: main.ProjectSpec{
+				AccessControl: main.AppProjectAccessControl{
+					ReadOnly: []string{
+						"sre:eng-2",
+					},
+					ReadSync: []string{
+						"sre:eng-0",
+						"sre:eng-1",
+					},
+				},
+				Environment:        "staging",
+				AllowAnySourceRepo: true,
+				ApplicationTemplates: []main.ApplicationTemplate{
+					main.ApplicationTemplate{
+						Application: argov1alpha1.Application{
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "github-checker-app",
+							},
+							Spec: argov1alpha1.ApplicationSpec{
+								Source: &argov1alpha1.ApplicationSource{
+									RepoURL: "https://github.com/t0rr3sp3dr0/github-checker.git",
+								},
+								Destination: argov1alpha1.ApplicationDestination{
+									Name:      "arn:aws:eks:us:123456789876:cluster/Global-SRE",
+									Namespace: "github-checker",
+								},
 							},
 						},
 					},
@@ -103,36 +218,40 @@ var _ = ginkgo.Describe("ArgoCDProject", func() {
 						},
 					},
 				},
-				ApplicationTemplates: []argov1alpha1.Application{
-					argov1alpha1.Application{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "github-checker-app",
-						},
-						Spec: argov1alpha1.ApplicationSpec{
-							Source: &argov1alpha1.ApplicationSource{
-								RepoURL:        "https://github.com/t0rr3sp3dr0/github-checker.git",
-								Path:           "namespaces/example/environment-overlays/env/cluster-overlays/cluster",
-								TargetRevision: "HEAD",
+				ApplicationTemplates: []main.ApplicationTemplate{
+					main.ApplicationTemplate{
+						Application: argov1alpha1.Application{
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "github-checker-app",
 							},
-							Destination: argov1alpha1.ApplicationDestination{
-								Name:      "arn:aws:eks:us:123456789876:cluster/Global-SRE",
-								Namespace: "github-checker",
+							Spec: argov1alpha1.ApplicationSpec{
+								Source: &argov1alpha1.ApplicationSource{
+									RepoURL:        "https://github.com/t0rr3sp3dr0/github-checker.git",
+									Path:           "namespaces/example/environment-overlays/env/cluster-overlays/cluster",
+									TargetRevision: "HEAD",
+								},
+								Destination: argov1alpha1.ApplicationDestination{
+									Name:      "arn:aws:eks:us:123456789876:cluster/Global-SRE",
+									Namespace: "github-checker",
+								},
 							},
 						},
 					},
-					argov1alpha1.Application{
-						ObjectMeta: metav1.ObjectMeta{
-							Name: "another-checker-app",
-						},
-						Spec: argov1alpha1.ApplicationSpec{
-							Source: &argov1alpha1.ApplicationSource{
-								RepoURL:        "https://github.com/t0rr3sp3dr0/another-checker.git",
-								Path:           "namespaces/example/environment-overlays/env/cluster-overlays/cluster",
-								TargetRevision: "HEAD",
+					main.ApplicationTemplate{
+						Application: argov1alpha1.Application{
+							ObjectMeta: metav1.ObjectMeta{
+								Name: "another-checker-app",
 							},
-							Destination: argov1alpha1.ApplicationDestination{
-								Name:      "arn:aws:eks:us:123456789876:cluster/Global-Product",
-								Namespace: "another-checker",
+							Spec: argov1alpha1.ApplicationSpec{
+								Source: &argov1alpha1.ApplicationSource{
+									RepoURL:        "https://github.com/t0rr3sp3dr0/another-checker.git",
+									Path:           "namespaces/example/environment-overlays/env/cluster-overlays/cluster",
+									TargetRevision: "HEAD",
+								},
+								Destination: argov1alpha1.ApplicationDestination{
+									Name:      "arn:aws:eks:us:123456789876:cluster/Global-Product",
+									Namespace: "another-checker",
+								},
 							},
 						},
 					},
@@ -142,6 +261,228 @@ var _ = ginkgo.Describe("ArgoCDProject", func() {
 	)
 })
 
+var _ = ginkgo.Describe("ArgoCDProject source repositories", func() {
+	multiSourceTemplate := newApplicationTemplate("github-checker-app", argov1alpha1.ApplicationDestination{})
+	multiSourceTemplate.Spec.Source = nil
+	multiSourceTemplate.Spec.Sources = argov1alpha1.ApplicationSources{
+		argov1alpha1.ApplicationSource{
+			RepoURL: "https://github.com/t0rr3sp3dr0/github-checker.git",
+		},
+		argov1alpha1.ApplicationSource{
+			RepoURL: "https://github.com/t0rr3sp3dr0/values.git",
+			Ref:     "values",
+		},
+	}
+
+	anotherTemplate := newApplicationTemplate("another-checker-app", argov1alpha1.ApplicationDestination{})
+	anotherTemplate.Spec.Source.RepoURL = "https://github.com/t0rr3sp3dr0/another-checker.git"
+
+	ginkgo.DescribeTable("", func(sourceRepos []string, expected []string) {
+		appProject := generateAppProject(newArgoCDProject(main.ProjectSpec{
+			AppProject: argov1alpha1.AppProject{
+				Spec: argov1alpha1.AppProjectSpec{
+					SourceRepos: sourceRepos,
+				},
+			},
+			ApplicationTemplates: []main.ApplicationTemplate{
+				multiSourceTemplate,
+				anotherTemplate,
+			},
+		}))
+		g.Expect(appProject.Spec.SourceRepos).To(g.Equal(expected))
+	},
+		ginkgo.Entry("derives repositories from every application source", nil, []string{
+			"https://github.com/t0rr3sp3dr0/another-checker.git",
+			"https://github.com/t0rr3sp3dr0/github-checker.git",
+			"https://github.com/t0rr3sp3dr0/values.git",
+		}),
+		ginkgo.Entry("merges explicit repositories with derived ones", []string{
+			"https://github.com/t0rr3sp3dr0/values.git",
+			"https://charts.example.com",
+		}, []string{
+			"https://github.com/t0rr3sp3dr0/values.git",
+			"https://charts.example.com",
+			"https://github.com/t0rr3sp3dr0/another-checker.git",
+			"https://github.com/t0rr3sp3dr0/github-checker.git",
+		}),
+		ginkgo.Entry("respects an explicit wildcard", []string{
+			"*",
+		}, []string{
+			"*",
+		}),
+	)
+})
+
+var _ = ginkgo.Describe("ArgoCDProject destinations", func() {
+	argoCDProject := newArgoCDProject(main.ProjectSpec{})
+	for _, destination := range []argov1alpha1.ApplicationDestination{
+		{Name: "Global-SRE", Namespace: "github-checker"},
+		{Server: "https://kubernetes.default.svc", Namespace: "github-checker"},
+		{Name: "Global-Product", Namespace: "another-checker"},
+		{Name: "Global-SRE", Namespace: "another-checker"},
+		{Name: "Global-FortKnox", Namespace: "foreground-checker"},
+		{Server: "https://kubernetes.default.svc", Namespace: "another-checker"},
+	} {
+		argoCDProject.Spec.ApplicationTemplates = append(argoCDProject.Spec.ApplicationTemplates,
+			newApplicationTemplate(fmt.Sprintf("%s-%s", destination.Namespace, destination.Name), destination))
+	}
+
+	ginkgo.It("sorts derived destinations by server, name and namespace", func() {
+		appProject := generateAppProject(argoCDProject)
+		g.Expect(appProject.Spec.Destinations).To(g.Equal([]argov1alpha1.ApplicationDestination{
+			{Name: "Global-FortKnox", Namespace: "foreground-checker"},
+			{Name: "Global-Product", Namespace: "another-checker"},
+			{Name: "Global-SRE", Namespace: "another-checker"},
+			{Name: "Global-SRE", Namespace: "github-checker"},
+			{Server: "https://kubernetes.default.svc", Namespace: "another-checker"},
+			{Server: "https://kubernetes.default.svc", Namespace: "github-checker"},
+		}))
+	})
+
+	ginkgo.It("generates byte-identical output on every run", func() {
+		data, err := yaml.Marshal(argoCDProject)
+		g.Expect(err).To(g.Succeed())
+
+		var expected bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &expected)).To(g.Succeed())
+
+		for i := 0; i < 100; i++ {
+			var actual bytes.Buffer
+			g.Expect(main.GenerateManifests(data, &actual)).To(g.Succeed())
+			g.Expect(actual.Bytes()).To(g.Equal(expected.Bytes()))
+		}
+	})
+})
+
+var _ = ginkgo.Describe("ArgoCDProject access control roles", func() {
+	ginkgo.DescribeTable("rejects", func(roles []main.AccessControlRole, message string) {
+		data, err := yaml.Marshal(newArgoCDProject(main.ProjectSpec{
+			AccessControl: main.AppProjectAccessControl{
+				Roles: roles,
+			},
+		}))
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(g.ContainSubstring(message)))
+	},
+		ginkgo.Entry("role without name", []main.AccessControlRole{
+			{},
+		}, "has no name"),
+		ginkgo.Entry("role shadowing a built-in role", []main.AccessControlRole{
+			{Name: main.ReadOnly.String()},
+		}, `"read-only" is already defined`),
+		ginkgo.Entry("duplicate role", []main.AccessControlRole{
+			{Name: "admin"},
+			{Name: "admin"},
+		}, `"admin" is already defined`),
+		ginkgo.Entry("unknown inherited role", []main.AccessControlRole{
+			{Name: "admin", Inherits: []string{"operator"}},
+		}, `"admin" inherits unknown role "operator"`),
+		ginkgo.Entry("inheritance cycle", []main.AccessControlRole{
+			{Name: "admin", Inherits: []string{"operator"}},
+			{Name: "operator", Inherits: []string{"admin"}},
+		}, "inherits itself"),
+	)
+})
+
+var _ = ginkgo.Describe("ArgoCDProject conventions", func() {
+	appTemplates := []main.ApplicationTemplate{
+		newApplicationTemplate("github-checker-app", argov1alpha1.ApplicationDestination{}),
+	}
+
+	ginkgo.DescribeTable("", func(conventions main.ProjectConventions, path string, targetRevision string) {
+		apps := generateApplications(newArgoCDProject(main.ProjectSpec{
+			Environment:          "production",
+			Conventions:          conventions,
+			ApplicationTemplates: appTemplates,
+		}))
+		g.Expect(apps).To(g.HaveLen(1))
+		g.Expect(apps[0].Spec.Source.Path).To(g.Equal(path))
+		g.Expect(apps[0].Spec.Source.TargetRevision).To(g.Equal(targetRevision))
+	},
+		ginkgo.Entry("evaluates custom path and target revision patterns", main.ProjectConventions{
+			Path:           "deploy/{{ .Environment }}/{{ .Application }}",
+			TargetRevision: "release/{{ .Project }}-{{ .Environment }}",
+		}, "deploy/production/github-checker-app", "release/github-checker-production"),
+		ginkgo.Entry("keeps the default patterns when omitted", main.ProjectConventions{
+			TargetRevision: "release/{{ .Environment }}",
+		}, "./k8s/overlays/production", "release/production"),
+	)
+
+	ginkgo.DescribeTable("rejects", func(conventions main.ProjectConventions, message string) {
+		data, err := yaml.Marshal(newArgoCDProject(main.ProjectSpec{
+			Environment:          "production",
+			Conventions:          conventions,
+			ApplicationTemplates: appTemplates,
+		}))
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(g.ContainSubstring(message)))
+	},
+		ginkgo.Entry("malformed pattern", main.ProjectConventions{
+			Path: "deploy/{{ .Environment",
+		}, "invalid path convention"),
+		ginkgo.Entry("unknown field", main.ProjectConventions{
+			TargetRevision: "release/{{ .Cluster }}",
+		}, `application "github-checker-app"`),
+	)
+})
+
+var _ = ginkgo.Describe("ArgoCDProject multi-source applications", func() {
+	argoCDProject := newArgoCDProject(main.ProjectSpec{
+		Environment: "production",
+		ApplicationTemplates: []main.ApplicationTemplate{
+			main.ApplicationTemplate{
+				Application: argov1alpha1.Application{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "github-checker-app",
+					},
+					Spec: argov1alpha1.ApplicationSpec{
+						Sources: argov1alpha1.ApplicationSources{
+							argov1alpha1.ApplicationSource{
+								RepoURL: "https://charts.example.com",
+								Chart:   "github-checker",
+								Helm: &argov1alpha1.ApplicationSourceHelm{
+									ValueFiles: []string{
+										"$values/values.yaml",
+									},
+								},
+								TargetRevision: "1.0.0",
+							},
+							argov1alpha1.ApplicationSource{
+								RepoURL:        "https://github.com/t0rr3sp3dr0/values.git",
+								TargetRevision: "HEAD",
+								Ref:            "values",
+							},
+							argov1alpha1.ApplicationSource{
+								RepoURL: "https://github.com/t0rr3sp3dr0/github-checker.git",
+							},
+						},
+					},
+				},
+			},
+		},
+	})
+
+	ginkgo.It("applies environment defaults to non-ref sources only", func() {
+		apps := generateApplications(argoCDProject)
+		g.Expect(apps).To(g.HaveLen(1))
+
+		g.Expect(apps[0].Spec.Source).To(g.BeNil())
+		g.Expect(apps[0].Spec.Sources).To(g.Equal(argov1alpha1.ApplicationSources{
+			argoCDProject.Spec.ApplicationTemplates[0].Spec.Sources[0],
+			argoCDProject.Spec.ApplicationTemplates[0].Spec.Sources[1],
+			argov1alpha1.ApplicationSource{
+				RepoURL:        "https://github.com/t0rr3sp3dr0/github-checker.git",
+				Path:           "./k8s/overlays/production",
+				TargetRevision: "env-production",
+			},
+		}))
+	})
+})
+
 func ArgoCDProject(argoCDProject main.ArgoCDProject) {
 	var argoCDProjectYaml []byte
 	if data, err := yaml.Marshal(argoCDProject); g.Expect(err).To(g.BeNil()) {
@@ -203,12 +544,46 @@ func ArgoCDProject(argoCDProject main.ArgoCDProject) {
 			g.HaveLen(len(destinations)),
 		)
 
+		sourceRepos := []string{
+			"*",
+		}
+		if !argoCDProject.Spec.AllowAnySourceRepo {
+			sourceRepoMap := make(map[string]bool)
+			for _, applicationTemplate := range argoCDProject.Spec.ApplicationTemplates {
+				sourceRepoMap[applicationTemplate.Spec.Source.RepoURL] = true
+			}
+
+			sourceRepos = make([]string, 0, len(sourceRepoMap))
+			for sourceRepo := range sourceRepoMap {
+				sourceRepos = append(sourceRepos, sourceRepo)
+			}
+		}
+		specSourceReposMatcher := g.And(
+			g.ContainElements(sourceRepos),
+			g.HaveLen(len(sourceRepos)),
+		)
+
+		specRolesElements := gstruct.Elements{
+			main.ReadOnly.String(): gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+				"Groups":   g.ContainElements(argoCDProject.Spec.AccessControl.ReadOnly),
+				"Policies": g.ContainElements(main.ReadOnly.Policies(argoCDProject.Name)),
+			}),
+			main.ReadSync.String(): gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+				"Groups":   g.ContainElements(argoCDProject.Spec.AccessControl.ReadSync),
+				"Policies": g.ContainElements(main.ReadSync.Policies(argoCDProject.Name)),
+			}),
+		}
+		for _, role := range argoCDProject.Spec.AccessControl.Roles {
+			specRolesElements[role.Name] = gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
+				"Groups":   g.Equal(role.Groups),
+				"Policies": g.Equal(role.Policies(argoCDProject.Name)),
+			})
+		}
+
 		g.Expect(appProject).To(gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
 			"ObjectMeta": g.Equal(argoCDProject.ObjectMeta),
 			"Spec": gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
-				"SourceRepos": g.Equal([]string{
-					"*",
-				}),
+				"SourceRepos":              specSourceReposMatcher,
 				"Destinations":             specDestinationsMatcher,
 				"ClusterResourceWhitelist": g.Equal(argoCDProject.Spec.AppProject.Spec.ClusterResourceWhitelist),
 				"NamespaceResourceWhitelist": g.Equal([]metav1.GroupKind{{
@@ -217,16 +592,7 @@ func ArgoCDProject(argoCDProject main.ArgoCDProject) {
 				}}),
 				"Roles": gstruct.MatchAllElements(func(e interface{}) string {
 					return e.(argov1alpha1.ProjectRole).Name
-				}, gstruct.Elements{
-					main.ReadOnly.String(): gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
-						"Groups":   g.ContainElements(argoCDProject.Spec.AccessControl.ReadOnly),
-						"Policies": g.ContainElements(main.ReadOnly.Policies(argoCDProject.Name, argoCDProject.Spec.Environment)),
-					}),
-					main.ReadSync.String(): gstruct.MatchFields(gstruct.IgnoreExtras, gstruct.Fields{
-						"Groups":   g.ContainElements(argoCDProject.Spec.AccessControl.ReadSync),
-						"Policies": g.ContainElements(main.ReadSync.Policies(argoCDProject.Name, argoCDProject.Spec.Environment)),
-					}),
-				}),
+				}, specRolesElements),
 			}),
 		}))
 	})
@@ -249,7 +615,7 @@ func ArgoCDProject(argoCDProject main.ArgoCDProject) {
 			var argoCdProjectApp argov1alpha1.Application
 			for _, appTemplate := range argoCDProject.Spec.ApplicationTemplates {
 				if app.Name == appTemplate.Name {
-					argoCdProjectApp = appTemplate
+					argoCdProjectApp = appTemplate.Application
 					break
 				}
 			}
@@ -288,3 +654,42 @@ func ArgoCDProject(argoCDProject main.ArgoCDProject) {
 		})
 	})
 }
+
+func generateManifests(argoCDProject main.ArgoCDProject) []string {
+	data, err := yaml.Marshal(argoCDProject)
+	g.Expect(err).To(g.Succeed())
+
+	var out bytes.Buffer
+	g.Expect(main.GenerateManifests(data, &out)).To(g.Succeed())
+
+	return separatorYaml.Split(out.String(), -1)
+}
+
+func generateAppProject(argoCDProject main.ArgoCDProject) argov1alpha1.AppProject {
+	var appProject argov1alpha1.AppProject
+	for _, manifest := range generateManifests(argoCDProject) {
+		var meta metav1.TypeMeta
+		g.Expect(yaml.Unmarshal([]byte(manifest), &meta)).To(g.Succeed())
+
+		if meta.GroupVersionKind() == argov1alpha1.AppProjectSchemaGroupVersionKind {
+			g.Expect(yaml.Unmarshal([]byte(manifest), &appProject)).To(g.Succeed())
+			break
+		}
+	}
+	return appProject
+}
+
+func generateApplications(argoCDProject main.ArgoCDProject) []argov1alpha1.Application {
+	var apps []argov1alpha1.Application
+	for _, manifest := range generateManifests(argoCDProject) {
+		var meta metav1.TypeMeta
+		g.Expect(yaml.Unmarshal([]byte(manifest), &meta)).To(g.Succeed())
+
+		if meta.GroupVersionKind() == argov1alpha1.ApplicationSchemaGroupVersionKind {
+			var app argov1alpha1.Application
+			g.Expect(yaml.Unmarshal([]byte(manifest), &app)).To(g.Succeed())
+			apps = append(apps, app)
+		}
+	}
+	return apps
+}
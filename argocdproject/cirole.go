@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+)
+
+var defaultCIRoleActions = []string{
+	"get",
+	"sync",
+}
+
+type CIRole struct {
+	Name         string   `json:"name"`
+	Description  string   `json:"description,omitempty"`
+	Applications string   `json:"applications"`
+	Actions      []string `json:"actions,omitempty"`
+}
+
+func (r CIRole) Policies(appProjectName string) []string {
+	actions := r.Actions
+	if len(actions) == 0 {
+		actions = defaultCIRoleActions
+	}
+
+	policies := make([]string, 0, len(actions))
+	for _, action := range actions {
+		policies = append(policies, fmt.Sprintf("p, proj:%[1]s:%[2]s, applications, %[3]s, %[1]s/%[4]s, allow", appProjectName, r.Name, action, r.Applications))
+	}
+	return policies
+}
+
+func makeCIProjectRoles(argocdProject *ArgoCDProject, appProject *argov1alpha1.AppProject) ([]argov1alpha1.ProjectRole, error) {
+	roles := argocdProject.Spec.AccessControl.CI
+
+	projectRoles := make([]argov1alpha1.ProjectRole, 0, len(roles))
+	for i, role := range roles {
+		if role.Name == "" {
+			return nil, fmt.Errorf("ci role %d has no name", i)
+		}
+		if role.Applications == "" {
+			return nil, fmt.Errorf("ci role %q has no applications", role.Name)
+		}
+
+		projectRoles = append(projectRoles, argov1alpha1.ProjectRole{
+			Name:        role.Name,
+			Description: role.Description,
+			Policies:    role.Policies(appProject.Name),
+		})
+	}
+
+	return projectRoles, nil
+}
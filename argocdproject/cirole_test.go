@@ -0,0 +1,96 @@
+package main_test
+
+import (
+	"bytes"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject CI roles", func() {
+	ginkgo.DescribeTable("emits roles scoped to the application glob", func(ciRole main.CIRole, expected argov1alpha1.ProjectRole) {
+		appProject := generateAppProject(newArgoCDProject(main.ProjectSpec{
+			AccessControl: main.AppProjectAccessControl{
+				CI: []main.CIRole{
+					ciRole,
+				},
+			},
+		}))
+		g.Expect(appProject.Spec.Roles).To(g.ContainElement(expected))
+	},
+		ginkgo.Entry("with default actions", main.CIRole{
+			Name:         "ci-api",
+			Description:  "Syncs the API applications from CI",
+			Applications: "api-*",
+		}, argov1alpha1.ProjectRole{
+			Name:        "ci-api",
+			Description: "Syncs the API applications from CI",
+			Policies: []string{
+				"p, proj:github-checker:ci-api, applications, get, github-checker/api-*, allow",
+				"p, proj:github-checker:ci-api, applications, sync, github-checker/api-*, allow",
+			},
+		}),
+		ginkgo.Entry("with explicit actions", main.CIRole{
+			Name:         "ci-worker",
+			Applications: "worker",
+			Actions: []string{
+				"sync",
+				"action/apps/Deployment/restart",
+			},
+		}, argov1alpha1.ProjectRole{
+			Name: "ci-worker",
+			Policies: []string{
+				"p, proj:github-checker:ci-worker, applications, sync, github-checker/worker, allow",
+				"p, proj:github-checker:ci-worker, applications, action/apps/Deployment/restart, github-checker/worker, allow",
+			},
+		}),
+	)
+
+	ginkgo.It("leaves JWT tokens for Argo CD to fill", func() {
+		for _, manifest := range generateManifests(newArgoCDProject(main.ProjectSpec{
+			AccessControl: main.AppProjectAccessControl{
+				CI: []main.CIRole{
+					main.CIRole{
+						Name:         "ci-api",
+						Applications: "api-*",
+					},
+				},
+			},
+		})) {
+			g.Expect(manifest).NotTo(g.ContainSubstring("jwtTokens"))
+		}
+	})
+
+	ginkgo.DescribeTable("rejects", func(ciRole main.CIRole, message string) {
+		data, err := yaml.Marshal(newArgoCDProject(main.ProjectSpec{
+			AccessControl: main.AppProjectAccessControl{
+				CI: []main.CIRole{
+					ciRole,
+				},
+			},
+		}))
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(g.ContainSubstring(message)))
+	},
+		ginkgo.Entry("role without name", main.CIRole{
+			Applications: "api-*",
+		}, "ci role 0 has no name"),
+		ginkgo.Entry("role without applications", main.CIRole{
+			Name: "ci-api",
+		}, `ci role "ci-api" has no applications`),
+		ginkgo.Entry("role with invalid application glob", main.CIRole{
+			Name:         "ci-api",
+			Applications: "api/*",
+		}, `role "ci-api" at index 2: invalid policy rule`),
+		ginkgo.Entry("role shadowing a built-in role", main.CIRole{
+			Name:         main.ReadSync.String(),
+			Applications: "*",
+		}, `role "read-sync" at index 2 is defined more than once, first at index 1`),
+	)
+})
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// applyDependencySyncWaves sets the sync wave of every application that
+// depends on, or is depended on by, another application. Applications without
+// dependencies are in wave 0, and every other application is in the wave after
+// the last of its dependencies.
+func applyDependencySyncWaves(appTemplates []ApplicationTemplate) error {
+	appTemplateMap := make(map[string]*ApplicationTemplate, len(appTemplates))
+	for i := range appTemplates {
+		appTemplateMap[appTemplates[i].Name] = &appTemplates[i]
+	}
+
+	dependencies := make(map[string]bool)
+	for _, appTemplate := range appTemplates {
+		if len(appTemplate.DependsOn) == 0 {
+			continue
+		}
+
+		dependencies[appTemplate.Name] = true
+		for _, dependency := range appTemplate.DependsOn {
+			if _, ok := appTemplateMap[dependency]; !ok {
+				return fmt.Errorf("application %q depends on unknown application %q", appTemplate.Name, dependency)
+			}
+			dependencies[dependency] = true
+		}
+	}
+
+	waves := make(map[string]int)
+	visiting := make(map[string]bool)
+	var visit func(name string) (int, error)
+	visit = func(name string) (int, error) {
+		if wave, ok := waves[name]; ok {
+			return wave, nil
+		}
+		if visiting[name] {
+			return 0, fmt.Errorf("application %q depends on itself", name)
+		}
+		visiting[name] = true
+
+		wave := 0
+		for _, dependency := range appTemplateMap[name].DependsOn {
+			dependencyWave, err := visit(dependency)
+			if err != nil {
+				return 0, err
+			}
+			if dependencyWave >= wave {
+				wave = dependencyWave + 1
+			}
+		}
+
+		visiting[name] = false
+		waves[name] = wave
+		return wave, nil
+	}
+
+	for i := range appTemplates {
+		app := &appTemplates[i].Application
+		if !dependencies[app.Name] {
+			continue
+		}
+
+		wave, err := visit(app.Name)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := app.Annotations[syncWaveAnnotation]; ok {
+			return fmt.Errorf("application %q cannot define a sync wave, as it is derived from dependsOn", app.Name)
+		}
+		if app.Annotations == nil {
+			app.Annotations = make(map[string]string)
+		}
+		app.Annotations[syncWaveAnnotation] = strconv.Itoa(wave)
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package main_test
+
+import (
+	"bytes"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject application dependencies", func() {
+	appTemplate := func(name string, annotations map[string]string, dependsOn ...string) main.ApplicationTemplate {
+		appTemplate := newApplicationTemplate(name, argov1alpha1.ApplicationDestination{})
+		appTemplate.Annotations = annotations
+		appTemplate.DependsOn = dependsOn
+		return appTemplate
+	}
+
+	syncWaves := func(apps []argov1alpha1.Application) map[string]string {
+		waves := make(map[string]string, len(apps))
+		for _, app := range apps {
+			if wave, ok := app.Annotations["argocd.argoproj.io/sync-wave"]; ok {
+				waves[app.Name] = wave
+			}
+		}
+		return waves
+	}
+
+	ginkgo.DescribeTable("", func(appTemplates []main.ApplicationTemplate, expected map[string]string) {
+		apps := generateApplications(newArgoCDProject(main.ProjectSpec{
+			ApplicationTemplates: appTemplates,
+		}))
+		g.Expect(syncWaves(apps)).To(g.Equal(expected))
+	},
+		ginkgo.Entry("assigns sync waves from the dependency graph", []main.ApplicationTemplate{
+			appTemplate("api", nil, "database", "cache"),
+			appTemplate("worker", nil, "api", "queue"),
+			appTemplate("database", nil),
+			appTemplate("cache", nil, "database"),
+			appTemplate("queue", nil),
+			appTemplate("dashboard", map[string]string{
+				"argocd.argoproj.io/sync-wave": "10",
+			}),
+		}, map[string]string{
+			"database":  "0",
+			"queue":     "0",
+			"cache":     "1",
+			"api":       "2",
+			"worker":    "3",
+			"dashboard": "10",
+		}),
+		ginkgo.Entry("leaves applications without dependencies untouched", []main.ApplicationTemplate{
+			appTemplate("api", nil),
+			appTemplate("worker", nil),
+		}, map[string]string{}),
+	)
+
+	ginkgo.DescribeTable("rejects", func(spec main.ProjectSpec, message string) {
+		data, err := yaml.Marshal(newArgoCDProject(spec))
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(message))
+	},
+		ginkgo.Entry("unknown dependency", main.ProjectSpec{
+			ApplicationTemplates: []main.ApplicationTemplate{
+				appTemplate("api", nil, "database"),
+			},
+		}, `application "api" depends on unknown application "database"`),
+		ginkgo.Entry("self dependency", main.ProjectSpec{
+			ApplicationTemplates: []main.ApplicationTemplate{
+				appTemplate("api", nil, "api"),
+			},
+		}, `application "api" depends on itself`),
+		ginkgo.Entry("dependency cycle", main.ProjectSpec{
+			ApplicationTemplates: []main.ApplicationTemplate{
+				appTemplate("api", nil, "worker"),
+				appTemplate("worker", nil, "queue"),
+				appTemplate("queue", nil, "api"),
+			},
+		}, `application "api" depends on itself`),
+		ginkgo.Entry("explicit sync wave", main.ProjectSpec{
+			ApplicationTemplates: []main.ApplicationTemplate{
+				appTemplate("api", map[string]string{
+					"argocd.argoproj.io/sync-wave": "1",
+				}, "database"),
+				appTemplate("database", nil),
+			},
+		}, `application "api" cannot define a sync wave, as it is derived from dependsOn`),
+		ginkgo.Entry("application set dependency", main.ProjectSpec{
+			ApplicationSetTemplates: []main.ApplicationSetTemplate{
+				main.ApplicationSetTemplate{
+					Template: appTemplate("api", nil, "database"),
+					Clusters: &metav1.LabelSelector{},
+				},
+			},
+		}, `application set "api" cannot depend on other applications`),
+	)
+})
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+)
+
+type ProjectEnvironment struct {
+	Name          string                              `json:"name"`
+	NameSuffix    *string                             `json:"nameSuffix,omitempty"`
+	Destination   argov1alpha1.ApplicationDestination `json:"destination,omitempty"`
+	AccessControl EnvironmentAccessControl            `json:"accessControl,omitempty"`
+}
+
+type EnvironmentAccessControl struct {
+	ReadOnly []string `json:"readOnly,omitempty"`
+	ReadSync []string `json:"readSync,omitempty"`
+}
+
+func (e *ProjectEnvironment) nameSuffix() string {
+	if e.NameSuffix != nil {
+		return *e.NameSuffix
+	}
+	return "-" + e.Name
+}
+
+// appProjectName returns the name of the AppProject generated for
+// argocdProject, which is suffixed when it was expanded from Environments.
+func (p *ArgoCDProject) appProjectName() string {
+	return p.Name + p.nameSuffix
+}
+
+// expandEnvironments returns one ArgoCDProject per entry of Environments, or
+// argocdProject itself when there are none. Each copy has its environment,
+// name suffix, access groups and destination overrides applied, so it can be
+// generated as if it had been written for that environment alone. As the
+// generated Applications are renamed with the name suffix, so are the
+// application patterns of CI roles and maintenance windows.
+func expandEnvironments(argocdProject *ArgoCDProject) ([]*ArgoCDProject, error) {
+	environments := argocdProject.Spec.Environments
+	if len(environments) == 0 {
+		return []*ArgoCDProject{
+			argocdProject,
+		}, nil
+	}
+
+	if argocdProject.Spec.Environment != "" {
+		return nil, fmt.Errorf("environment and environments cannot both be set")
+	}
+
+	names := make(map[string]bool, len(environments))
+	nameSuffixes := make(map[string]string, len(environments))
+	argocdProjects := make([]*ArgoCDProject, 0, len(environments))
+	for i := range environments {
+		environment := &environments[i]
+
+		if environment.Name == "" {
+			return nil, fmt.Errorf("environment %d has no name", i)
+		}
+		if names[environment.Name] {
+			return nil, fmt.Errorf("environment %q is already defined", environment.Name)
+		}
+		names[environment.Name] = true
+
+		nameSuffix := environment.nameSuffix()
+		if other, ok := nameSuffixes[nameSuffix]; ok {
+			return nil, fmt.Errorf("environments %q and %q have the same name suffix %q", other, environment.Name, nameSuffix)
+		}
+		nameSuffixes[nameSuffix] = environment.Name
+
+		environmentProject, err := copyArgoCDProject(argocdProject)
+		if err != nil {
+			return nil, err
+		}
+		environmentProject.nameSuffix = nameSuffix
+
+		spec := &environmentProject.Spec
+		spec.Environment = environment.Name
+		spec.Environments = nil
+		spec.AccessControl.ReadOnly = append(spec.AccessControl.ReadOnly, environment.AccessControl.ReadOnly...)
+		spec.AccessControl.ReadSync = append(spec.AccessControl.ReadSync, environment.AccessControl.ReadSync...)
+
+		for j := range spec.AccessControl.CI {
+			ciRole := &spec.AccessControl.CI[j]
+			ciRole.Applications = suffixApplicationPattern(ciRole.Applications, nameSuffix)
+		}
+		for j := range spec.MaintenanceWindows {
+			applications := spec.MaintenanceWindows[j].Applications
+			for k := range applications {
+				applications[k] = suffixApplicationPattern(applications[k], nameSuffix)
+			}
+		}
+
+		for j := range spec.ApplicationTemplates {
+			overrideDestination(&spec.ApplicationTemplates[j].Spec.Destination, environment.Destination)
+		}
+		for j := range spec.ApplicationSetTemplates {
+			overrideDestination(&spec.ApplicationSetTemplates[j].Template.Spec.Destination, environment.Destination)
+		}
+
+		argocdProjects = append(argocdProjects, environmentProject)
+	}
+
+	return argocdProjects, nil
+}
+
+// suffixApplicationPattern appends nameSuffix to an application name or glob,
+// so that it matches the suffixed Applications. The catch-all pattern already
+// does.
+func suffixApplicationPattern(pattern string, nameSuffix string) string {
+	if pattern == applicationAll {
+		return pattern
+	}
+	return pattern + nameSuffix
+}
+
+// overrideDestination replaces the fields of destination that are set on
+// override. Server and name are replaced together, since only one of them may
+// be set.
+func overrideDestination(destination *argov1alpha1.ApplicationDestination, override argov1alpha1.ApplicationDestination) {
+	if override.Server != "" || override.Name != "" {
+		destination.Server = override.Server
+		destination.Name = override.Name
+	}
+	if override.Namespace != "" {
+		destination.Namespace = override.Namespace
+	}
+}
+
+func copyArgoCDProject(argocdProject *ArgoCDProject) (*ArgoCDProject, error) {
+	b, err := json.Marshal(argocdProject)
+	if err != nil {
+		return nil, err
+	}
+
+	var copied ArgoCDProject
+	if err := json.Unmarshal(b, &copied); err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
@@ -0,0 +1,233 @@
+package main_test
+
+import (
+	"bytes"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject environments", func() {
+	emptySuffix := ""
+
+	destination := argov1alpha1.ApplicationDestination{
+		Name:      "Global-SRE",
+		Namespace: "github-checker",
+	}
+
+	worker := newApplicationTemplate("worker", destination)
+	worker.DependsOn = []string{
+		"api",
+	}
+
+	spec := main.ProjectSpec{
+		AccessControl: main.AppProjectAccessControl{
+			ReadOnly: []string{
+				"sre:eng",
+			},
+		},
+		ApplicationTemplates: []main.ApplicationTemplate{
+			newApplicationTemplate("api", destination),
+			worker,
+		},
+	}
+
+	staging := main.ProjectEnvironment{
+		Name: "staging",
+		Destination: argov1alpha1.ApplicationDestination{
+			Server: "https://staging.k8s.incognia.com",
+		},
+		AccessControl: main.EnvironmentAccessControl{
+			ReadSync: []string{
+				"sre:eng",
+			},
+		},
+	}
+
+	production := main.ProjectEnvironment{
+		Name:       "production",
+		NameSuffix: &emptySuffix,
+		Destination: argov1alpha1.ApplicationDestination{
+			Name:      "Global-Production",
+			Namespace: "github-checker-prod",
+		},
+		AccessControl: main.EnvironmentAccessControl{
+			ReadOnly: []string{
+				"sre:oncall",
+			},
+		},
+	}
+
+	generateAppProjects := func(argoCDProject main.ArgoCDProject) map[string]argov1alpha1.AppProject {
+		appProjects := make(map[string]argov1alpha1.AppProject)
+		for _, manifest := range generateManifests(argoCDProject) {
+			var meta metav1.TypeMeta
+			g.Expect(yaml.Unmarshal([]byte(manifest), &meta)).To(g.Succeed())
+
+			if meta.GroupVersionKind() == argov1alpha1.AppProjectSchemaGroupVersionKind {
+				var appProject argov1alpha1.AppProject
+				g.Expect(yaml.Unmarshal([]byte(manifest), &appProject)).To(g.Succeed())
+				appProjects[appProject.Name] = appProject
+			}
+		}
+		return appProjects
+	}
+
+	ginkgo.It("emits one AppProject per environment", func() {
+		project := newArgoCDProject(spec)
+		project.Spec.Environments = []main.ProjectEnvironment{
+			staging,
+			production,
+		}
+
+		appProjects := generateAppProjects(project)
+		g.Expect(appProjects).To(g.HaveLen(2))
+
+		stagingProject := appProjects["github-checker-staging"]
+		g.Expect(stagingProject.Spec.Destinations).To(g.Equal([]argov1alpha1.ApplicationDestination{
+			argov1alpha1.ApplicationDestination{
+				Server:    "https://staging.k8s.incognia.com",
+				Namespace: "github-checker",
+			},
+		}))
+		g.Expect(stagingProject.Spec.Roles[0].Groups).To(g.Equal([]string{"sre:eng"}))
+		g.Expect(stagingProject.Spec.Roles[1].Groups).To(g.Equal([]string{"sre:eng"}))
+		g.Expect(stagingProject.Spec.Roles[1].Policies).To(g.ContainElement("p, proj:github-checker-staging:read-sync, applications, override, github-checker-staging/*, allow"))
+
+		productionProject := appProjects["github-checker"]
+		g.Expect(productionProject.Spec.Destinations).To(g.Equal([]argov1alpha1.ApplicationDestination{
+			argov1alpha1.ApplicationDestination{
+				Name:      "Global-Production",
+				Namespace: "github-checker-prod",
+			},
+		}))
+		g.Expect(productionProject.Spec.Roles[0].Groups).To(g.Equal([]string{"sre:eng", "sre:oncall"}))
+		g.Expect(productionProject.Spec.Roles[1].Groups).To(g.BeEmpty())
+	})
+
+	ginkgo.It("emits the applications of every environment", func() {
+		project := newArgoCDProject(spec)
+		project.Spec.Environments = []main.ProjectEnvironment{
+			staging,
+			production,
+		}
+
+		apps := generateApplications(project)
+
+		names := make([]string, 0, len(apps))
+		for _, app := range apps {
+			names = append(names, app.Name)
+		}
+		g.Expect(names).To(g.Equal([]string{"api-staging", "worker-staging", "api", "worker"}))
+
+		g.Expect(apps[0].Spec.Project).To(g.Equal("github-checker-staging"))
+		g.Expect(apps[0].Spec.Source.Path).To(g.Equal("./k8s/overlays/staging"))
+		g.Expect(apps[0].Spec.Source.TargetRevision).To(g.Equal("env-staging"))
+		g.Expect(apps[1].Annotations).To(g.HaveKeyWithValue("argocd.argoproj.io/sync-wave", "1"))
+
+		g.Expect(apps[2].Spec.Project).To(g.Equal("github-checker"))
+		g.Expect(apps[2].Spec.Destination).To(g.Equal(argov1alpha1.ApplicationDestination{
+			Name:      "Global-Production",
+			Namespace: "github-checker-prod",
+		}))
+		g.Expect(apps[2].Spec.Source.TargetRevision).To(g.Equal("env-production"))
+	})
+
+	ginkgo.It("suffixes the applications of CI roles and maintenance windows", func() {
+		project := newArgoCDProject(spec)
+		project.Spec.Environments = []main.ProjectEnvironment{
+			staging,
+			production,
+		}
+		project.Spec.AccessControl.CI = []main.CIRole{
+			main.CIRole{
+				Name:         "deploy",
+				Applications: "api",
+				Actions: []string{
+					"sync",
+				},
+			},
+		}
+		project.Spec.MaintenanceWindows = []main.MaintenanceWindow{
+			main.MaintenanceWindow{
+				Kind: "deny",
+				Applications: []string{
+					"api",
+					"*",
+				},
+			},
+		}
+
+		appProjects := generateAppProjects(project)
+
+		stagingProject := appProjects["github-checker-staging"]
+		g.Expect(stagingProject.Spec.Roles).To(g.ContainElement(g.HaveField("Policies", []string{
+			"p, proj:github-checker-staging:deploy, applications, sync, github-checker-staging/api-staging, allow",
+		})))
+		g.Expect(stagingProject.Spec.SyncWindows).To(g.HaveLen(1))
+		g.Expect(stagingProject.Spec.SyncWindows[0].Applications).To(g.Equal([]string{"api-staging", "*"}))
+
+		productionProject := appProjects["github-checker"]
+		g.Expect(productionProject.Spec.Roles).To(g.ContainElement(g.HaveField("Policies", []string{
+			"p, proj:github-checker:deploy, applications, sync, github-checker/api, allow",
+		})))
+		g.Expect(productionProject.Spec.SyncWindows).To(g.HaveLen(1))
+		g.Expect(productionProject.Spec.SyncWindows[0].Applications).To(g.Equal([]string{"api", "*"}))
+	})
+
+	ginkgo.It("keeps the original project when no environments are defined", func() {
+		apps := generateApplications(newArgoCDProject(spec))
+		g.Expect(apps).To(g.HaveLen(2))
+		g.Expect(apps[0].Name).To(g.Equal("api"))
+		g.Expect(apps[0].Spec.Project).To(g.Equal("github-checker"))
+		g.Expect(apps[0].Spec.Source.TargetRevision).To(g.BeEmpty())
+	})
+
+	ginkgo.DescribeTable("rejects", func(mutate func(*main.ArgoCDProject), message string) {
+		project := newArgoCDProject(spec)
+		project.Spec.Environments = []main.ProjectEnvironment{
+			staging,
+			production,
+		}
+		mutate(&project)
+
+		data, err := yaml.Marshal(project)
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(g.ContainSubstring(message)))
+	},
+		ginkgo.Entry("both environment and environments", func(project *main.ArgoCDProject) {
+			project.Spec.Environment = "staging"
+		}, "environment and environments cannot both be set"),
+		ginkgo.Entry("an environment without name", func(project *main.ArgoCDProject) {
+			project.Spec.Environments[0].Name = ""
+		}, "environment 0 has no name"),
+		ginkgo.Entry("duplicate environments", func(project *main.ArgoCDProject) {
+			project.Spec.Environments[1].Name = "staging"
+		}, `environment "staging" is already defined`),
+		ginkgo.Entry("duplicate name suffixes", func(project *main.ArgoCDProject) {
+			project.Spec.Environments[0].NameSuffix = &emptySuffix
+		}, `environments "staging" and "production" have the same name suffix ""`),
+		ginkgo.Entry("an environment destination with both server and name", func(project *main.ArgoCDProject) {
+			project.Spec.Environments[0].Destination = argov1alpha1.ApplicationDestination{
+				Server: "https://kubernetes.default.svc",
+				Name:   "in-cluster",
+			}
+		}, "spec.environments[0].destination: Invalid value"),
+		ginkgo.Entry("namespaces emitted by several environments", func(project *main.ArgoCDProject) {
+			project.Spec.EmitNamespaces = true
+			project.Spec.Environments[0].Destination = argov1alpha1.ApplicationDestination{
+				Name: "in-cluster",
+			}
+			project.Spec.Environments[1].Destination = argov1alpha1.ApplicationDestination{
+				Server: "https://kubernetes.default.svc",
+			}
+		}, `namespace "github-checker" is emitted by environments "staging" and "production"`),
+	)
+})
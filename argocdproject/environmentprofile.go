@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	policyEffectAllow = "allow"
+	policyEffectDeny  = "deny"
+)
+
+var builtinEnvironmentProfiles = []EnvironmentProfile{
+	EnvironmentProfile{
+		Environment: stagingEnvironment,
+		Roles: []EnvironmentProfileRole{
+			EnvironmentProfileRole{
+				Role: ReadSync.String(),
+				Allow: []string{
+					"override",
+				},
+			},
+		},
+	},
+}
+
+type EnvironmentProfile struct {
+	Environment       string                                         `json:"environment"`
+	Roles             []EnvironmentProfileRole                       `json:"roles,omitempty"`
+	OrphanedResources *argov1alpha1.OrphanedResourcesMonitorSettings `json:"orphanedResources,omitempty"`
+	SignatureKeys     []string                                       `json:"signatureKeys,omitempty"`
+}
+
+type EnvironmentProfileRole struct {
+	Role  string   `json:"role"`
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+func (r EnvironmentProfileRole) Policies(appProjectName string) []string {
+	policies := make([]string, 0, len(r.Allow)+len(r.Deny))
+	for _, action := range r.Allow {
+		policies = append(policies, fmt.Sprintf("p, proj:%[1]s:%[2]s, applications, %[3]s, %[1]s/*, %[4]s", appProjectName, r.Role, action, policyEffectAllow))
+	}
+	for _, action := range r.Deny {
+		policies = append(policies, fmt.Sprintf("p, proj:%[1]s:%[2]s, applications, %[3]s, %[1]s/*, %[4]s", appProjectName, r.Role, action, policyEffectDeny))
+	}
+	return policies
+}
+
+func (p *EnvironmentProfile) Policies(appProjectName string, role string) []string {
+	if p == nil {
+		return nil
+	}
+
+	var policies []string
+	for _, profileRole := range p.Roles {
+		if profileRole.Role == role {
+			policies = append(policies, profileRole.Policies(appProjectName)...)
+		}
+	}
+	return policies
+}
+
+// resolveEnvironmentProfile returns the profile of the project environment.
+// Profiles defined inline take precedence over the ones read from
+// EnvironmentProfilesFile, which take precedence over the built-in ones. A
+// relative EnvironmentProfilesFile is resolved from the working directory,
+// which Kustomize sets to the kustomization directory.
+func resolveEnvironmentProfile(argocdProject *ArgoCDProject) (*EnvironmentProfile, error) {
+	environment := argocdProject.Spec.Environment
+	if environment == "" {
+		return nil, nil
+	}
+
+	var fileProfiles []EnvironmentProfile
+	if filePath := argocdProject.Spec.EnvironmentProfilesFile; filePath != "" {
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := yaml.Unmarshal(data, &fileProfiles); err != nil {
+			return nil, fmt.Errorf("%s: %w", filePath, err)
+		}
+	}
+
+	var profile *EnvironmentProfile
+	for _, profiles := range [][]EnvironmentProfile{builtinEnvironmentProfiles, fileProfiles, argocdProject.Spec.EnvironmentProfiles} {
+		for i := range profiles {
+			if profiles[i].Environment == environment {
+				profile = &profiles[i]
+			}
+		}
+	}
+	if profile == nil {
+		return nil, nil
+	}
+	return profile.deepCopy(), nil
+}
+
+// deepCopy returns a copy of p sharing no memory with it, so that the
+// built-in profiles cannot be modified through the resolved one.
+func (p *EnvironmentProfile) deepCopy() *EnvironmentProfile {
+	copied := *p
+
+	copied.Roles = make([]EnvironmentProfileRole, 0, len(p.Roles))
+	for _, role := range p.Roles {
+		role.Allow = append([]string(nil), role.Allow...)
+		role.Deny = append([]string(nil), role.Deny...)
+		copied.Roles = append(copied.Roles, role)
+	}
+
+	if p.OrphanedResources != nil {
+		copied.OrphanedResources = p.OrphanedResources.DeepCopy()
+	}
+	copied.SignatureKeys = append([]string(nil), p.SignatureKeys...)
+
+	return &copied
+}
+
+func validateEnvironmentProfile(profile *EnvironmentProfile, roleNames map[string]bool) error {
+	if profile == nil {
+		return nil
+	}
+
+	for _, profileRole := range profile.Roles {
+		if !roleNames[profileRole.Role] {
+			return fmt.Errorf("environment profile %q references unknown role %q", profile.Environment, profileRole.Role)
+		}
+	}
+	return nil
+}
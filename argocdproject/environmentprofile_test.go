@@ -0,0 +1,148 @@
+package main_test
+
+import (
+	"bytes"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject environment profiles", func() {
+	accessControl := main.AppProjectAccessControl{
+		Roles: []main.AccessControlRole{
+			main.AccessControlRole{
+				Name: "admin",
+				Actions: []string{
+					"delete",
+				},
+			},
+		},
+	}
+
+	profiles := []main.EnvironmentProfile{
+		main.EnvironmentProfile{
+			Environment: "dev",
+			Roles: []main.EnvironmentProfileRole{
+				main.EnvironmentProfileRole{
+					Role: main.ReadSync.String(),
+					Allow: []string{
+						"override",
+					},
+				},
+			},
+		},
+		main.EnvironmentProfile{
+			Environment: "production",
+			Roles: []main.EnvironmentProfileRole{
+				main.EnvironmentProfileRole{
+					Role: main.ReadSync.String(),
+					Deny: []string{
+						"action/apps/Deployment/restart",
+					},
+				},
+				main.EnvironmentProfileRole{
+					Role: "admin",
+					Allow: []string{
+						"update",
+					},
+					Deny: []string{
+						"delete",
+					},
+				},
+			},
+		},
+	}
+
+	ginkgo.DescribeTable("", func(spec main.ProjectSpec, roleName string, policies []string) {
+		spec.AccessControl = accessControl
+
+		var role argov1alpha1.ProjectRole
+		appProject := generateAppProject(newArgoCDProject(spec))
+		g.Expect(appProject.Spec.Roles).To(g.ContainElement(g.HaveField("Name", roleName), &role))
+		g.Expect(role.Policies).To(g.Equal(policies))
+	},
+		ginkgo.Entry("grants override to read-sync on staging by default", main.ProjectSpec{
+			Environment: "staging",
+		}, main.ReadSync.String(), append(
+			main.ReadSync.Policies("github-checker"),
+			"p, proj:github-checker:read-sync, applications, override, github-checker/*, allow",
+		)),
+		ginkgo.Entry("grants nothing extra to read-only on environments without profile", main.ProjectSpec{
+			Environment: "production",
+		}, main.ReadOnly.String(), main.ReadOnly.Policies("github-checker")),
+		ginkgo.Entry("grants nothing extra to read-sync on environments without profile", main.ProjectSpec{
+			Environment: "production",
+		}, main.ReadSync.String(), main.ReadSync.Policies("github-checker")),
+		ginkgo.Entry("applies deny policies of the environment profile", main.ProjectSpec{
+			Environment:         "production",
+			EnvironmentProfiles: profiles,
+		}, main.ReadSync.String(), append(
+			main.ReadSync.Policies("github-checker"),
+			"p, proj:github-checker:read-sync, applications, action/apps/Deployment/restart, github-checker/*, deny",
+		)),
+		ginkgo.Entry("applies allow and deny policies of the environment profile to custom roles", main.ProjectSpec{
+			Environment:         "production",
+			EnvironmentProfiles: profiles,
+		}, "admin", []string{
+			"p, proj:github-checker:admin, applications, delete, github-checker/*, allow",
+			"p, proj:github-checker:admin, applications, update, github-checker/*, allow",
+			"p, proj:github-checker:admin, applications, delete, github-checker/*, deny",
+		}),
+		ginkgo.Entry("replaces the built-in profile of the same environment", main.ProjectSpec{
+			Environment: "staging",
+			EnvironmentProfiles: []main.EnvironmentProfile{
+				main.EnvironmentProfile{
+					Environment: "staging",
+				},
+			},
+		}, main.ReadSync.String(), main.ReadSync.Policies("github-checker")),
+		ginkgo.Entry("reads profiles from a shared file relative to the kustomization directory", main.ProjectSpec{
+			Environment:             "qa",
+			EnvironmentProfilesFile: "testdata/environment-profiles.yaml",
+		}, main.ReadSync.String(), append(
+			main.ReadSync.Policies("github-checker"),
+			"p, proj:github-checker:read-sync, applications, override, github-checker/*, allow",
+		)),
+		ginkgo.Entry("prefers inline profiles over the shared file", main.ProjectSpec{
+			Environment:             "qa",
+			EnvironmentProfilesFile: "testdata/environment-profiles.yaml",
+			EnvironmentProfiles: []main.EnvironmentProfile{
+				main.EnvironmentProfile{
+					Environment: "qa",
+				},
+			},
+		}, main.ReadSync.String(), main.ReadSync.Policies("github-checker")),
+	)
+
+	ginkgo.DescribeTable("rejects", func(spec main.ProjectSpec, message string) {
+		spec.AccessControl = accessControl
+
+		data, err := yaml.Marshal(newArgoCDProject(spec))
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(g.ContainSubstring(message)))
+	},
+		ginkgo.Entry("profile for unknown role", main.ProjectSpec{
+			Environment: "production",
+			EnvironmentProfiles: []main.EnvironmentProfile{
+				main.EnvironmentProfile{
+					Environment: "production",
+					Roles: []main.EnvironmentProfileRole{
+						main.EnvironmentProfileRole{
+							Role: "operator",
+						},
+					},
+				},
+			},
+		}, `environment profile "production" references unknown role "operator"`),
+		ginkgo.Entry("missing profiles file", main.ProjectSpec{
+			Environment:             "production",
+			EnvironmentProfilesFile: "/nonexistent/profiles.yaml",
+		}, "/nonexistent/profiles.yaml"),
+	)
+})
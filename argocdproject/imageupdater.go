@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	separatorImageList       = ", "
+	separatorImageAlias      = "="
+	separatorImageVersion    = ":"
+	separatorImageUpdaterKey = "."
+
+	imageUpdaterAnnotationPrefix = "argocd-image-updater.argoproj.io/"
+
+	imageUpdaterImageListKey       = "image-list"
+	imageUpdaterWriteBackMethodKey = "write-back-method"
+	imageUpdaterUpdateStrategyKey  = "update-strategy"
+	imageUpdaterAllowTagsKey       = "allow-tags"
+
+	imageUpdaterAllowTagsRegexpPrefix = "regexp:"
+
+	writeBackMethodArgoCD          = "argocd"
+	writeBackMethodGit             = "git"
+	writeBackMethodGitRepoCreds    = "git:repocreds"
+	writeBackMethodGitSecretPrefix = "git:secret:"
+)
+
+var (
+	imageAliasRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([-_a-zA-Z0-9]*[a-zA-Z0-9])?$`)
+	secretRefRegexp  = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?/[a-z0-9]([-.a-z0-9]*[a-z0-9])?$`)
+
+	imageUpdateStrategies = map[string]bool{
+		"semver":       true,
+		"digest":       true,
+		"newest-build": true,
+		"alphabetical": true,
+		"latest":       true,
+		"name":         true,
+	}
+)
+
+type ImageUpdater struct {
+	Images          []ImageUpdaterImage `json:"images"`
+	WriteBackMethod string              `json:"writeBackMethod,omitempty"`
+}
+
+type ImageUpdaterImage struct {
+	Alias          string `json:"alias"`
+	Image          string `json:"image"`
+	Constraint     string `json:"constraint,omitempty"`
+	UpdateStrategy string `json:"updateStrategy,omitempty"`
+	AllowTags      string `json:"allowTags,omitempty"`
+}
+
+// Annotations returns the Argo CD Image Updater annotations tracking the
+// images. The update strategies, tag regular expressions and write-back
+// method are validated, as Argo CD Image Updater silently skips applications
+// with invalid ones.
+func (u *ImageUpdater) Annotations() (map[string]string, error) {
+	if len(u.Images) == 0 {
+		return nil, fmt.Errorf("no images")
+	}
+
+	annotations := make(map[string]string)
+
+	aliases := make(map[string]bool, len(u.Images))
+	imageList := make([]string, 0, len(u.Images))
+	for i, image := range u.Images {
+		if image.Alias == "" {
+			return nil, fmt.Errorf("image %d has no alias", i)
+		}
+		if !imageAliasRegexp.MatchString(image.Alias) {
+			return nil, fmt.Errorf("image %d has invalid alias %q", i, image.Alias)
+		}
+		if errs := validation.IsQualifiedName(imageUpdaterAnnotationKey(image.Alias, imageUpdaterUpdateStrategyKey)); len(errs) != 0 {
+			return nil, fmt.Errorf("image %d has invalid alias %q: %s", i, image.Alias, strings.Join(errs, "; "))
+		}
+		if aliases[image.Alias] {
+			return nil, fmt.Errorf("image alias %q is already defined", image.Alias)
+		}
+		aliases[image.Alias] = true
+
+		if image.Image == "" {
+			return nil, fmt.Errorf("image %q has no image", image.Alias)
+		}
+
+		imageRef := image.Alias + separatorImageAlias + image.Image
+		if image.Constraint != "" {
+			imageRef += separatorImageVersion + image.Constraint
+		}
+		imageList = append(imageList, imageRef)
+
+		if image.UpdateStrategy != "" {
+			if !imageUpdateStrategies[image.UpdateStrategy] {
+				return nil, fmt.Errorf("image %q has unknown update strategy %q", image.Alias, image.UpdateStrategy)
+			}
+			annotations[imageUpdaterAnnotationKey(image.Alias, imageUpdaterUpdateStrategyKey)] = image.UpdateStrategy
+		}
+
+		if image.AllowTags != "" {
+			if _, err := regexp.Compile(image.AllowTags); err != nil {
+				return nil, fmt.Errorf("image %q has invalid allowed tags: %w", image.Alias, err)
+			}
+			annotations[imageUpdaterAnnotationKey(image.Alias, imageUpdaterAllowTagsKey)] = imageUpdaterAllowTagsRegexpPrefix + image.AllowTags
+		}
+	}
+	annotations[imageUpdaterAnnotationPrefix+imageUpdaterImageListKey] = strings.Join(imageList, separatorImageList)
+
+	if u.WriteBackMethod != "" {
+		if err := validateWriteBackMethod(u.WriteBackMethod); err != nil {
+			return nil, err
+		}
+		annotations[imageUpdaterAnnotationPrefix+imageUpdaterWriteBackMethodKey] = u.WriteBackMethod
+	}
+
+	return annotations, nil
+}
+
+func imageUpdaterAnnotationKey(alias string, key string) string {
+	return imageUpdaterAnnotationPrefix + alias + separatorImageUpdaterKey + key
+}
+
+// validateWriteBackMethod checks method is either argocd, git, git:repocreds
+// or git:secret:<namespace>/<name>.
+func validateWriteBackMethod(method string) error {
+	switch {
+	case method == writeBackMethodArgoCD, method == writeBackMethodGit, method == writeBackMethodGitRepoCreds:
+		return nil
+	case strings.HasPrefix(method, writeBackMethodGitSecretPrefix):
+		if secretRef := strings.TrimPrefix(method, writeBackMethodGitSecretPrefix); !secretRefRegexp.MatchString(secretRef) {
+			return fmt.Errorf("invalid write-back method %q: secret must be formatted as <namespace>/<name>", method)
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid write-back method %q: must be %s, %s, %s or %s<namespace>/<name>", method, writeBackMethodArgoCD, writeBackMethodGit, writeBackMethodGitRepoCreds, writeBackMethodGitSecretPrefix)
+	}
+}
+
+// applyImageUpdater stamps the Argo CD Image Updater annotations onto app.
+// Annotations already defined on the application win.
+func applyImageUpdater(imageUpdater *ImageUpdater, app *argov1alpha1.Application) error {
+	if imageUpdater == nil {
+		return nil
+	}
+
+	annotations, err := imageUpdater.Annotations()
+	if err != nil {
+		return fmt.Errorf("application %q: image updater: %w", app.Name, err)
+	}
+
+	for key, value := range annotations {
+		if _, ok := app.Annotations[key]; ok {
+			continue
+		}
+		if app.Annotations == nil {
+			app.Annotations = make(map[string]string)
+		}
+		app.Annotations[key] = value
+	}
+
+	return nil
+}
@@ -0,0 +1,139 @@
+package main_test
+
+import (
+	"bytes"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject image updater", func() {
+	ginkgo.DescribeTable("", func(annotations map[string]string, imageUpdater *main.ImageUpdater, expected map[string]string) {
+		appTemplate := newApplicationTemplate("github-checker", argov1alpha1.ApplicationDestination{})
+		appTemplate.Annotations = annotations
+		appTemplate.ImageUpdater = imageUpdater
+		argoCDProject := newArgoCDProject(main.ProjectSpec{
+			ApplicationTemplates: []main.ApplicationTemplate{
+				appTemplate,
+			},
+		})
+
+		apps := generateApplications(argoCDProject)
+		g.Expect(apps).To(g.HaveLen(1))
+		g.Expect(apps[0].Annotations).To(g.Equal(expected))
+	},
+		ginkgo.Entry("leaves applications without image updater untouched", nil, nil, nil),
+		ginkgo.Entry("renders the image updater annotations", nil, &main.ImageUpdater{
+			Images: []main.ImageUpdaterImage{
+				main.ImageUpdaterImage{
+					Alias:          "api",
+					Image:          "ghcr.io/t0rr3sp3dr0/github-checker",
+					Constraint:     "1.x",
+					UpdateStrategy: "semver",
+				},
+				main.ImageUpdaterImage{
+					Alias:          "worker",
+					Image:          "ghcr.io/t0rr3sp3dr0/github-checker-worker",
+					UpdateStrategy: "newest-build",
+					AllowTags:      `^main-[0-9a-f]{7}$`,
+				},
+			},
+			WriteBackMethod: "git:secret:argocd/git-creds",
+		}, map[string]string{
+			"argocd-image-updater.argoproj.io/image-list":             "api=ghcr.io/t0rr3sp3dr0/github-checker:1.x, worker=ghcr.io/t0rr3sp3dr0/github-checker-worker",
+			"argocd-image-updater.argoproj.io/api.update-strategy":    "semver",
+			"argocd-image-updater.argoproj.io/worker.update-strategy": "newest-build",
+			"argocd-image-updater.argoproj.io/worker.allow-tags":      "regexp:^main-[0-9a-f]{7}$",
+			"argocd-image-updater.argoproj.io/write-back-method":      "git:secret:argocd/git-creds",
+		}),
+		ginkgo.Entry("lets applications override annotations", map[string]string{
+			"argocd-image-updater.argoproj.io/write-back-method": "argocd",
+		}, &main.ImageUpdater{
+			Images: []main.ImageUpdaterImage{
+				main.ImageUpdaterImage{
+					Alias: "api",
+					Image: "ghcr.io/t0rr3sp3dr0/github-checker",
+				},
+			},
+			WriteBackMethod: "git",
+		}, map[string]string{
+			"argocd-image-updater.argoproj.io/image-list":        "api=ghcr.io/t0rr3sp3dr0/github-checker",
+			"argocd-image-updater.argoproj.io/write-back-method": "argocd",
+		}),
+	)
+
+	ginkgo.DescribeTable("rejects", func(imageUpdater main.ImageUpdater, message string) {
+		appTemplate := newApplicationTemplate("github-checker", argov1alpha1.ApplicationDestination{})
+		appTemplate.ImageUpdater = &imageUpdater
+
+		data, err := yaml.Marshal(newArgoCDProject(main.ProjectSpec{
+			ApplicationTemplates: []main.ApplicationTemplate{
+				appTemplate,
+			},
+		}))
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(g.ContainSubstring(message)))
+	},
+		ginkgo.Entry("no images", main.ImageUpdater{}, `application "github-checker": image updater: no images`),
+		ginkgo.Entry("image without alias", main.ImageUpdater{
+			Images: []main.ImageUpdaterImage{
+				main.ImageUpdaterImage{
+					Image: "ghcr.io/t0rr3sp3dr0/github-checker",
+				},
+			},
+		}, "image 0 has no alias"),
+		ginkgo.Entry("image with invalid alias", main.ImageUpdater{
+			Images: []main.ImageUpdaterImage{
+				main.ImageUpdaterImage{
+					Alias: "api.v2",
+					Image: "ghcr.io/t0rr3sp3dr0/github-checker",
+				},
+			},
+		}, `image 0 has invalid alias "api.v2"`),
+		ginkgo.Entry("duplicate alias", main.ImageUpdater{
+			Images: []main.ImageUpdaterImage{
+				main.ImageUpdaterImage{
+					Alias: "api",
+					Image: "ghcr.io/t0rr3sp3dr0/github-checker",
+				},
+				main.ImageUpdaterImage{
+					Alias: "api",
+					Image: "ghcr.io/t0rr3sp3dr0/github-checker-worker",
+				},
+			},
+		}, `image alias "api" is already defined`),
+		ginkgo.Entry("unknown update strategy", main.ImageUpdater{
+			Images: []main.ImageUpdaterImage{
+				main.ImageUpdaterImage{
+					Alias:          "api",
+					Image:          "ghcr.io/t0rr3sp3dr0/github-checker",
+					UpdateStrategy: "semvar",
+				},
+			},
+		}, `image "api" has unknown update strategy "semvar"`),
+		ginkgo.Entry("invalid allowed tags", main.ImageUpdater{
+			Images: []main.ImageUpdaterImage{
+				main.ImageUpdaterImage{
+					Alias:     "api",
+					Image:     "ghcr.io/t0rr3sp3dr0/github-checker",
+					AllowTags: "^v[0-9+$",
+				},
+			},
+		}, `image "api" has invalid allowed tags`),
+		ginkgo.Entry("invalid write-back method", main.ImageUpdater{
+			Images: []main.ImageUpdaterImage{
+				main.ImageUpdaterImage{
+					Alias: "api",
+					Image: "ghcr.io/t0rr3sp3dr0/github-checker",
+				},
+			},
+			WriteBackMethod: "git:secret:git-creds",
+		}, `invalid write-back method "git:secret:git-creds"`),
+	)
+})
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strings"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	kustomizeBehaviorAnnotation  = "kustomize.config.k8s.io/behavior"
+	kustomizeNeedsHashAnnotation = "kustomize.config.k8s.io/needs-hash"
+)
+
+var (
+	kustomizeInternalAnnotationPrefixes = []string{
+		"config.kubernetes.io/",
+		"internal.config.kubernetes.io/",
+		"kustomize.config.k8s.io/",
+	}
+)
+
+type MetadataPropagation struct {
+	Labels      bool `json:"labels,omitempty"`
+	Annotations bool `json:"annotations,omitempty"`
+}
+
+// propagateMetadata copies the labels and annotations of the ArgoCDProject
+// to objectMeta, as configured by PropagateMetadata. The generator options
+// Kustomize reads from generated resources, behavior and needs-hash, are
+// always copied, while the other annotations Kustomize sets on the generator
+// itself, such as its path and index, never are. Labels and annotations
+// already defined on objectMeta win.
+func propagateMetadata(argocdProject *ArgoCDProject, objectMeta *metav1.ObjectMeta) {
+	objectMeta.Labels, objectMeta.Annotations = mergePropagatedMetadata(argocdProject, objectMeta.Labels, objectMeta.Annotations, true)
+}
+
+// propagateTemplateMetadata is like propagateMetadata for the Application
+// template of an ApplicationSet, except that the generator options are not
+// copied, as the Applications it renders are not Kustomize resources.
+func propagateTemplateMetadata(argocdProject *ArgoCDProject, templateMeta *argov1alpha1.ApplicationSetTemplateMeta) {
+	templateMeta.Labels, templateMeta.Annotations = mergePropagatedMetadata(argocdProject, templateMeta.Labels, templateMeta.Annotations, false)
+}
+
+func mergePropagatedMetadata(argocdProject *ArgoCDProject, labels map[string]string, annotations map[string]string, generatorOptions bool) (map[string]string, map[string]string) {
+	propagation := argocdProject.Spec.PropagateMetadata
+
+	if propagation.Labels {
+		labels = mergeMetadata(argocdProject.Labels, labels)
+	}
+
+	propagated := make(map[string]string)
+	for key, value := range argocdProject.Annotations {
+		switch {
+		case key == kustomizeBehaviorAnnotation, key == kustomizeNeedsHashAnnotation:
+			if generatorOptions {
+				propagated[key] = value
+			}
+		case isKustomizeInternalAnnotation(key):
+			continue
+		case propagation.Annotations:
+			propagated[key] = value
+		}
+	}
+	annotations = mergeMetadata(propagated, annotations)
+
+	return labels, annotations
+}
+
+func isKustomizeInternalAnnotation(key string) bool {
+	for _, prefix := range kustomizeInternalAnnotationPrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func mergeMetadata(defaults map[string]string, overrides map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return overrides
+	}
+
+	merged := make(map[string]string, len(defaults)+len(overrides))
+	for key, value := range defaults {
+		merged[key] = value
+	}
+	for key, value := range overrides {
+		merged[key] = value
+	}
+	return merged
+}
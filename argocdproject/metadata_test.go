@@ -0,0 +1,149 @@
+package main_test
+
+import (
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject metadata propagation", func() {
+	labels := map[string]string{
+		"team":        "sre",
+		"cost-center": "platform",
+	}
+	annotations := map[string]string{
+		"owner":                              "sre@incognia.com",
+		"kustomize.config.k8s.io/behavior":   "merge",
+		"kustomize.config.k8s.io/needs-hash": "false",
+		"kustomize.config.k8s.io/id":         "1",
+		"config.kubernetes.io/path":          "employees.argoCDProject.yaml",
+		"config.kubernetes.io/index":         "0",
+		"config.kubernetes.io/local-config":  "true",
+		"internal.config.kubernetes.io/annotations-migration-resource-id": "0",
+	}
+
+	appProjectTemplate := argov1alpha1.AppProject{
+		ObjectMeta: metav1.ObjectMeta{
+			Labels: map[string]string{
+				"team": "sre-projects",
+			},
+		},
+	}
+
+	appTemplate := newApplicationTemplate("github-checker", argov1alpha1.ApplicationDestination{})
+	appTemplate.Annotations = map[string]string{
+		"owner": "checker@incognia.com",
+	}
+
+	ginkgo.It("only propagates the Kustomize generator options by default", func() {
+		project := newArgoCDProject(main.ProjectSpec{
+			PropagateMetadata: main.MetadataPropagation{},
+			AppProject:        appProjectTemplate,
+			ApplicationTemplates: []main.ApplicationTemplate{
+				appTemplate,
+			},
+		})
+		project.Labels = labels
+		project.Annotations = annotations
+
+		appProject := generateAppProject(project)
+		g.Expect(appProject.Labels).To(g.Equal(map[string]string{
+			"team": "sre-projects",
+		}))
+		g.Expect(appProject.Annotations).To(g.Equal(map[string]string{
+			"kustomize.config.k8s.io/behavior":   "merge",
+			"kustomize.config.k8s.io/needs-hash": "false",
+		}))
+
+		apps := generateApplications(project)
+		g.Expect(apps).To(g.HaveLen(1))
+		g.Expect(apps[0].Labels).To(g.BeEmpty())
+		g.Expect(apps[0].Annotations).To(g.Equal(map[string]string{
+			"owner":                              "checker@incognia.com",
+			"kustomize.config.k8s.io/behavior":   "merge",
+			"kustomize.config.k8s.io/needs-hash": "false",
+		}))
+	})
+
+	ginkgo.It("propagates labels and annotations to every output", func() {
+		project := newArgoCDProject(main.ProjectSpec{
+			PropagateMetadata: main.MetadataPropagation{
+				Labels:      true,
+				Annotations: true,
+			},
+			AppProject: appProjectTemplate,
+			ApplicationTemplates: []main.ApplicationTemplate{
+				appTemplate,
+			},
+		})
+		project.Labels = labels
+		project.Annotations = annotations
+
+		appProject := generateAppProject(project)
+		g.Expect(appProject.Labels).To(g.Equal(map[string]string{
+			"team":        "sre-projects",
+			"cost-center": "platform",
+		}))
+		g.Expect(appProject.Annotations).To(g.Equal(map[string]string{
+			"owner":                              "sre@incognia.com",
+			"kustomize.config.k8s.io/behavior":   "merge",
+			"kustomize.config.k8s.io/needs-hash": "false",
+		}))
+
+		apps := generateApplications(project)
+		g.Expect(apps).To(g.HaveLen(1))
+		g.Expect(apps[0].Labels).To(g.Equal(map[string]string{
+			"team":        "sre",
+			"cost-center": "platform",
+		}))
+		g.Expect(apps[0].Annotations).To(g.Equal(map[string]string{
+			"owner":                              "checker@incognia.com",
+			"kustomize.config.k8s.io/behavior":   "merge",
+			"kustomize.config.k8s.io/needs-hash": "false",
+		}))
+	})
+
+	ginkgo.It("propagates labels and annotations to application sets", func() {
+		project := newArgoCDProject(main.ProjectSpec{
+			PropagateMetadata: main.MetadataPropagation{
+				Labels:      true,
+				Annotations: true,
+			},
+			AppProject: appProjectTemplate,
+			ApplicationTemplates: []main.ApplicationTemplate{
+				appTemplate,
+			},
+		})
+		project.Labels = labels
+		project.Annotations = annotations
+		project.Spec.ApplicationSetTemplates = []main.ApplicationSetTemplate{
+			main.ApplicationSetTemplate{
+				Template: appTemplate,
+				Clusters: &metav1.LabelSelector{},
+			},
+		}
+		project.Spec.ApplicationTemplates = nil
+
+		appSets := generateApplicationSets(project)
+		g.Expect(appSets).To(g.HaveLen(1))
+		g.Expect(appSets[0].Labels).To(g.Equal(map[string]string{
+			"team":        "sre",
+			"cost-center": "platform",
+		}))
+		g.Expect(appSets[0].Annotations).To(g.Equal(map[string]string{
+			"owner":                              "sre@incognia.com",
+			"kustomize.config.k8s.io/behavior":   "merge",
+			"kustomize.config.k8s.io/needs-hash": "false",
+		}))
+		g.Expect(appSets[0].Spec.Template.Labels).To(g.Equal(map[string]string{
+			"team":        "sre",
+			"cost-center": "platform",
+		}))
+		g.Expect(appSets[0].Spec.Template.Annotations).To(g.Equal(map[string]string{
+			"owner": "checker@incognia.com",
+		}))
+	})
+})
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	inClusterName = "in-cluster"
+)
+
+// NamespacedRole is the ClusterRole, and the name of the RoleBinding, the
+// Namespace generator grants to the groups of the matching access level.
+func (a accessLevel) NamespacedRole() string {
+	switch a {
+	case ReadOnly:
+		return "namespaced-ro"
+	case ReadSync:
+		return "namespaced-rw"
+	default:
+		panic(fmt.Sprintf("unknown access level %d", a))
+	}
+}
+
+func (a accessLevel) NamespacedGroup(namespace string) string {
+	switch a {
+	case ReadOnly:
+		return fmt.Sprintf("%s:ro", namespace)
+	case ReadSync:
+		return fmt.Sprintf("%s:rw", namespace)
+	default:
+		panic(fmt.Sprintf("unknown access level %d", a))
+	}
+}
+
+func isInClusterDestination(destination argov1alpha1.ApplicationDestination) bool {
+	return destination.Server == argov1alpha1.KubernetesInternalAPIServerAddr || destination.Name == inClusterName
+}
+
+func destinationNamespaces(argocdProject *ArgoCDProject) []string {
+	var destinations []argov1alpha1.ApplicationDestination
+	for _, app := range argocdProject.Spec.ApplicationTemplates {
+		destinations = append(destinations, app.Spec.Destination)
+	}
+	for i := range argocdProject.Spec.ApplicationSetTemplates {
+		destinations = append(destinations, argocdProject.Spec.ApplicationSetTemplates[i].destinations()...)
+	}
+
+	namespaceSet := make(map[string]bool)
+	for _, destination := range destinations {
+		if destination.Namespace == "" || !isInClusterDestination(destination) {
+			continue
+		}
+		namespaceSet[destination.Namespace] = true
+	}
+
+	namespaces := make([]string, 0, len(namespaceSet))
+	for namespace := range namespaceSet {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	return namespaces
+}
+
+func makeNamespaces(argocdProject *ArgoCDProject) ([][]byte, error) {
+	if !argocdProject.Spec.EmitNamespaces {
+		return nil, nil
+	}
+
+	var manifests [][]byte
+	for _, namespace := range destinationNamespaces(argocdProject) {
+		ns, err := makeNamespace(namespace)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, ns)
+
+		readOnlyRoleBinding, err := makeRoleBinding(ReadOnly, namespace, argocdProject.Spec.AccessControl.ReadOnly)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, readOnlyRoleBinding)
+
+		readSyncRoleBinding, err := makeRoleBinding(ReadSync, namespace, argocdProject.Spec.AccessControl.ReadSync)
+		if err != nil {
+			return nil, err
+		}
+		manifests = append(manifests, readSyncRoleBinding)
+	}
+
+	return manifests, nil
+}
+
+func makeNamespace(namespace string) ([]byte, error) {
+	ns := corev1.Namespace{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       reflect.TypeOf(corev1.Namespace{}).Name(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: namespace,
+		},
+	}
+
+	return marshalYAMLWithoutStatusField(ns)
+}
+
+func makeRoleBinding(accessLevel accessLevel, namespace string, groups []string) ([]byte, error) {
+	names := make([]string, 0, len(groups)+1)
+	names = append(names, groups...)
+	names = append(names, accessLevel.NamespacedGroup(namespace))
+
+	subjects := make([]rbacv1.Subject, 0, len(names))
+	for _, name := range names {
+		subjects = append(subjects, rbacv1.Subject{
+			APIGroup: rbacv1.GroupName,
+			Kind:     rbacv1.GroupKind,
+			Name:     name,
+		})
+	}
+
+	roleBinding := rbacv1.RoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: rbacv1.SchemeGroupVersion.String(),
+			Kind:       reflect.TypeOf(rbacv1.RoleBinding{}).Name(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      accessLevel.NamespacedRole(),
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     reflect.TypeOf(rbacv1.ClusterRole{}).Name(),
+			Name:     accessLevel.NamespacedRole(),
+		},
+		Subjects: subjects,
+	}
+
+	return yaml.Marshal(roleBinding)
+}
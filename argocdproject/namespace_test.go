@@ -0,0 +1,136 @@
+package main_test
+
+import (
+	"reflect"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject destination namespaces", func() {
+	namespaceGVK := corev1.SchemeGroupVersion.WithKind(reflect.TypeOf(corev1.Namespace{}).Name())
+	roleBindingGVK := rbacv1.SchemeGroupVersion.WithKind(reflect.TypeOf(rbacv1.RoleBinding{}).Name())
+
+	spec := main.ProjectSpec{
+		AccessControl: main.AppProjectAccessControl{
+			ReadOnly: []string{
+				"sre:eng-1",
+			},
+			ReadSync: []string{
+				"sre:eng-0",
+			},
+		},
+		ApplicationTemplates: []main.ApplicationTemplate{
+			newApplicationTemplate("github-checker-api", argov1alpha1.ApplicationDestination{
+				Server:    argov1alpha1.KubernetesInternalAPIServerAddr,
+				Namespace: "github-checker",
+			}),
+			newApplicationTemplate("github-checker-worker", argov1alpha1.ApplicationDestination{
+				Name:      "in-cluster",
+				Namespace: "github-checker",
+			}),
+			newApplicationTemplate("github-checker-jobs", argov1alpha1.ApplicationDestination{
+				Name:      "in-cluster",
+				Namespace: "github-checker-jobs",
+			}),
+			newApplicationTemplate("github-checker-remote", argov1alpha1.ApplicationDestination{
+				Name:      "GlobalStaging-Product",
+				Namespace: "github-checker-remote",
+			}),
+		},
+	}
+
+	emittingSpec := spec
+	emittingSpec.EmitNamespaces = true
+
+	generateResources := func(argoCDProject main.ArgoCDProject) ([]corev1.Namespace, []rbacv1.RoleBinding) {
+		var namespaces []corev1.Namespace
+		var roleBindings []rbacv1.RoleBinding
+		for _, manifest := range generateManifests(argoCDProject) {
+			var meta metav1.TypeMeta
+			g.Expect(yaml.Unmarshal([]byte(manifest), &meta)).To(g.Succeed())
+
+			switch meta.GroupVersionKind() {
+			case namespaceGVK:
+				var namespace corev1.Namespace
+				g.Expect(yaml.Unmarshal([]byte(manifest), &namespace)).To(g.Succeed())
+				namespaces = append(namespaces, namespace)
+			case roleBindingGVK:
+				var roleBinding rbacv1.RoleBinding
+				g.Expect(yaml.Unmarshal([]byte(manifest), &roleBinding)).To(g.Succeed())
+				roleBindings = append(roleBindings, roleBinding)
+			}
+		}
+		return namespaces, roleBindings
+	}
+
+	ginkgo.It("emits nothing by default", func() {
+		namespaces, roleBindings := generateResources(newArgoCDProject(spec))
+		g.Expect(namespaces).To(g.BeEmpty())
+		g.Expect(roleBindings).To(g.BeEmpty())
+	})
+
+	ginkgo.It("emits in-cluster destination namespaces", func() {
+		namespaces, _ := generateResources(newArgoCDProject(emittingSpec))
+
+		var names []string
+		for _, namespace := range namespaces {
+			names = append(names, namespace.Name)
+		}
+		g.Expect(names).To(g.Equal([]string{
+			"github-checker",
+			"github-checker-jobs",
+		}))
+	})
+
+	ginkgo.It("binds the project groups like the Namespace generator", func() {
+		_, roleBindings := generateResources(newArgoCDProject(emittingSpec))
+		g.Expect(roleBindings).To(g.HaveLen(4))
+
+		g.Expect(roleBindings[0].Namespace).To(g.Equal("github-checker"))
+		g.Expect(roleBindings[0].Name).To(g.Equal("namespaced-ro"))
+		g.Expect(roleBindings[0].RoleRef).To(g.Equal(rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "ClusterRole",
+			Name:     "namespaced-ro",
+		}))
+		g.Expect(roleBindings[0].Subjects).To(g.Equal([]rbacv1.Subject{
+			rbacv1.Subject{
+				APIGroup: rbacv1.GroupName,
+				Kind:     rbacv1.GroupKind,
+				Name:     "sre:eng-1",
+			},
+			rbacv1.Subject{
+				APIGroup: rbacv1.GroupName,
+				Kind:     rbacv1.GroupKind,
+				Name:     "github-checker:ro",
+			},
+		}))
+
+		g.Expect(roleBindings[1].Namespace).To(g.Equal("github-checker"))
+		g.Expect(roleBindings[1].Name).To(g.Equal("namespaced-rw"))
+		g.Expect(roleBindings[1].RoleRef.Name).To(g.Equal("namespaced-rw"))
+		g.Expect(roleBindings[1].Subjects).To(g.Equal([]rbacv1.Subject{
+			rbacv1.Subject{
+				APIGroup: rbacv1.GroupName,
+				Kind:     rbacv1.GroupKind,
+				Name:     "sre:eng-0",
+			},
+			rbacv1.Subject{
+				APIGroup: rbacv1.GroupName,
+				Kind:     rbacv1.GroupKind,
+				Name:     "github-checker:rw",
+			},
+		}))
+
+		g.Expect(roleBindings[2].Namespace).To(g.Equal("github-checker-jobs"))
+		g.Expect(roleBindings[3].Namespace).To(g.Equal("github-checker-jobs"))
+	})
+})
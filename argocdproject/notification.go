@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	separatorNotificationKey        = "."
+	separatorNotificationRecipients = ";"
+
+	notificationSubscribeAnnotationPrefix = "notifications.argoproj.io/subscribe."
+)
+
+var (
+	notificationNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9]([-_a-zA-Z0-9]*[a-zA-Z0-9])?$`)
+)
+
+type NotificationSubscription struct {
+	Trigger    string   `json:"trigger,omitempty"`
+	Service    string   `json:"service"`
+	Recipients []string `json:"recipients"`
+}
+
+// Annotation returns the key and value of the subscription annotation. When
+// no trigger is set, the default triggers of the service are subscribed to.
+func (s NotificationSubscription) Annotation() (string, string, error) {
+	if s.Service == "" {
+		return "", "", fmt.Errorf("no service")
+	}
+	if len(s.Recipients) == 0 {
+		return "", "", fmt.Errorf("no recipients")
+	}
+
+	var key string
+	if s.Trigger == "" {
+		key = notificationSubscribeAnnotationPrefix + s.Service
+	} else {
+		key = notificationSubscribeAnnotationPrefix + s.Trigger + separatorNotificationKey + s.Service
+	}
+	if err := validateNotificationAnnotation(key); err != nil {
+		return "", "", err
+	}
+
+	return key, strings.Join(s.Recipients, separatorNotificationRecipients), nil
+}
+
+func applyNotificationSubscriptions(argocdProject *ArgoCDProject, app *argov1alpha1.Application) error {
+	for i, subscription := range argocdProject.Spec.Notifications {
+		key, value, err := subscription.Annotation()
+		if err != nil {
+			return fmt.Errorf("notification subscription %d: %w", i, err)
+		}
+
+		if _, ok := app.Annotations[key]; ok {
+			continue
+		}
+		if app.Annotations == nil {
+			app.Annotations = make(map[string]string)
+		}
+		app.Annotations[key] = value
+	}
+
+	keys := make([]string, 0, len(app.Annotations))
+	for key := range app.Annotations {
+		if strings.HasPrefix(key, notificationSubscribeAnnotationPrefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		// An empty value on the application unsubscribes it from a project subscription.
+		if app.Annotations[key] == "" {
+			delete(app.Annotations, key)
+			continue
+		}
+
+		if err := validateNotificationAnnotation(key); err != nil {
+			return fmt.Errorf("application %q: %w", app.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// validateNotificationAnnotation checks key is formatted as
+// notifications.argoproj.io/subscribe.<trigger>.<service> or
+// notifications.argoproj.io/subscribe.<service>.
+func validateNotificationAnnotation(key string) error {
+	if errs := validation.IsQualifiedName(key); len(errs) != 0 {
+		return fmt.Errorf("invalid notification annotation %q: %s", key, strings.Join(errs, "; "))
+	}
+
+	names := strings.Split(strings.TrimPrefix(key, notificationSubscribeAnnotationPrefix), separatorNotificationKey)
+	if len(names) > 2 {
+		return fmt.Errorf("invalid notification annotation %q: must be formatted as %s<trigger>.<service>", key, notificationSubscribeAnnotationPrefix)
+	}
+	for _, name := range names {
+		if !notificationNameRegexp.MatchString(name) {
+			return fmt.Errorf("invalid notification annotation %q: invalid trigger or service name %q", key, name)
+		}
+	}
+
+	return nil
+}
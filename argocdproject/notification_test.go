@@ -0,0 +1,112 @@
+package main_test
+
+import (
+	"bytes"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject notification subscriptions", func() {
+	onSyncFailed := main.NotificationSubscription{
+		Trigger: "on-sync-failed",
+		Service: "slack",
+		Recipients: []string{
+			"team-channel",
+			"sre-channel",
+		},
+	}
+
+	ginkgo.DescribeTable("", func(annotations map[string]string, subscriptions []main.NotificationSubscription, expected map[string]string) {
+		appTemplate := newApplicationTemplate("github-checker", argov1alpha1.ApplicationDestination{})
+		appTemplate.Annotations = annotations
+		argoCDProject := newArgoCDProject(main.ProjectSpec{
+			Notifications: subscriptions,
+			ApplicationTemplates: []main.ApplicationTemplate{
+				appTemplate,
+			},
+		})
+
+		apps := generateApplications(argoCDProject)
+		g.Expect(apps).To(g.HaveLen(1))
+		g.Expect(apps[0].Annotations).To(g.Equal(expected))
+	},
+		ginkgo.Entry("stamps subscriptions onto every application", nil, []main.NotificationSubscription{
+			onSyncFailed,
+			main.NotificationSubscription{
+				Service: "webhook",
+				Recipients: []string{
+					"github",
+				},
+			},
+		}, map[string]string{
+			"notifications.argoproj.io/subscribe.on-sync-failed.slack": "team-channel;sre-channel",
+			"notifications.argoproj.io/subscribe.webhook":              "github",
+		}),
+		ginkgo.Entry("lets applications override subscriptions", map[string]string{
+			"notifications.argoproj.io/subscribe.on-sync-failed.slack":     "another-channel",
+			"notifications.argoproj.io/subscribe.on-deployed.slack":        "",
+			"notifications.argoproj.io/subscribe.on-health-degraded.teams": "oncall",
+		}, []main.NotificationSubscription{
+			onSyncFailed,
+			main.NotificationSubscription{
+				Trigger: "on-deployed",
+				Service: "slack",
+				Recipients: []string{
+					"team-channel",
+				},
+			},
+		}, map[string]string{
+			"notifications.argoproj.io/subscribe.on-sync-failed.slack":     "another-channel",
+			"notifications.argoproj.io/subscribe.on-health-degraded.teams": "oncall",
+		}),
+	)
+
+	ginkgo.DescribeTable("rejects", func(annotations map[string]string, subscriptions []main.NotificationSubscription, message string) {
+		appTemplate := newApplicationTemplate("github-checker", argov1alpha1.ApplicationDestination{})
+		appTemplate.Annotations = annotations
+		argoCDProject := newArgoCDProject(main.ProjectSpec{
+			Notifications: subscriptions,
+			ApplicationTemplates: []main.ApplicationTemplate{
+				appTemplate,
+			},
+		})
+
+		data, err := yaml.Marshal(argoCDProject)
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(g.ContainSubstring(message)))
+	},
+		ginkgo.Entry("subscription without recipients", nil, []main.NotificationSubscription{
+			main.NotificationSubscription{
+				Trigger: "on-sync-failed",
+				Service: "slack",
+			},
+		}, "notification subscription 0: no recipients"),
+		ginkgo.Entry("subscription with invalid trigger", nil, []main.NotificationSubscription{
+			main.NotificationSubscription{
+				Trigger: "on sync failed",
+				Service: "slack",
+				Recipients: []string{
+					"team-channel",
+				},
+			},
+		}, `invalid notification annotation "notifications.argoproj.io/subscribe.on sync failed.slack"`),
+		ginkgo.Entry("subscription without service", nil, []main.NotificationSubscription{
+			main.NotificationSubscription{
+				Trigger: "on-sync-failed",
+				Recipients: []string{
+					"team-channel",
+				},
+			},
+		}, "notification subscription 0: no service"),
+		ginkgo.Entry("application annotation with too many segments", map[string]string{
+			"notifications.argoproj.io/subscribe.on-sync-failed.slack.team": "team-channel",
+		}, nil, `application "github-checker": invalid notification annotation "notifications.argoproj.io/subscribe.on-sync-failed.slack.team"`),
+	)
+})
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/argoproj/argo-cd/v2/util/gpg"
+)
+
+// applyOrphanedResources sets the orphaned resources monitoring settings of
+// appProjectSpec. The settings of the environment profile are overridden by
+// the project ones, which are overridden by the ones of the AppProject
+// template.
+func applyOrphanedResources(argocdProject *ArgoCDProject, environmentProfile *EnvironmentProfile, appProjectSpec *argov1alpha1.AppProjectSpec) error {
+	var layers []*argov1alpha1.OrphanedResourcesMonitorSettings
+	if environmentProfile != nil {
+		layers = append(layers, environmentProfile.OrphanedResources)
+	}
+	layers = append(layers, argocdProject.Spec.OrphanedResources, appProjectSpec.OrphanedResources)
+
+	var merged *argov1alpha1.OrphanedResourcesMonitorSettings
+	for _, layer := range layers {
+		if layer == nil {
+			continue
+		}
+		if merged == nil {
+			merged = layer.DeepCopy()
+			continue
+		}
+
+		var orphanedResources argov1alpha1.OrphanedResourcesMonitorSettings
+		if err := mergeJSON(merged, layer, &orphanedResources); err != nil {
+			return err
+		}
+		merged = &orphanedResources
+	}
+	appProjectSpec.OrphanedResources = merged
+
+	return nil
+}
+
+// applySignatureKeys appends the signature keys of the project and of the
+// environment profile to the ones of the AppProject template. Every key must
+// be a short or long GnuPG key ID, as accepted by the Argo CD CLI.
+func applySignatureKeys(argocdProject *ArgoCDProject, environmentProfile *EnvironmentProfile, appProjectSpec *argov1alpha1.AppProjectSpec) error {
+	keyIDs := make([]string, 0, len(appProjectSpec.SignatureKeys)+len(argocdProject.Spec.SignatureKeys))
+	for _, signatureKey := range appProjectSpec.SignatureKeys {
+		keyIDs = append(keyIDs, signatureKey.KeyID)
+	}
+	keyIDs = append(keyIDs, argocdProject.Spec.SignatureKeys...)
+	if environmentProfile != nil {
+		keyIDs = append(keyIDs, environmentProfile.SignatureKeys...)
+	}
+
+	seen := make(map[string]bool, len(keyIDs))
+	var signatureKeys []argov1alpha1.SignatureKey
+	for _, keyID := range keyIDs {
+		if !gpg.IsShortKeyID(keyID) && !gpg.IsLongKeyID(keyID) {
+			return fmt.Errorf("invalid signature key %q: must be a 16 or 40 digit hexadecimal GnuPG key ID", keyID)
+		}
+
+		if !seen[keyID] {
+			seen[keyID] = true
+			signatureKeys = append(signatureKeys, argov1alpha1.SignatureKey{
+				KeyID: keyID,
+			})
+		}
+	}
+	appProjectSpec.SignatureKeys = signatureKeys
+
+	return nil
+}
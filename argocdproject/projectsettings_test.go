@@ -0,0 +1,123 @@
+package main_test
+
+import (
+	"bytes"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject orphaned resources and signature keys", func() {
+	const (
+		shortKeyID = "4AEE18F83AFDEB23"
+		longKeyID  = "5DE3E0509C47EA3CF04A42D34AEE18F83AFDEB23"
+	)
+
+	warn := true
+	noWarn := false
+
+	profiles := []main.EnvironmentProfile{
+		main.EnvironmentProfile{
+			Environment: "production",
+			OrphanedResources: &argov1alpha1.OrphanedResourcesMonitorSettings{
+				Warn: &warn,
+			},
+			SignatureKeys: []string{
+				longKeyID,
+			},
+		},
+	}
+
+	ginkgo.It("leaves both unset by default", func() {
+		appProject := generateAppProject(newArgoCDProject(main.ProjectSpec{
+			Environment:         "staging",
+			EnvironmentProfiles: profiles,
+		}))
+		g.Expect(appProject.Spec.OrphanedResources).To(g.BeNil())
+		g.Expect(appProject.Spec.SignatureKeys).To(g.BeEmpty())
+	})
+
+	ginkgo.It("applies the defaults of the environment profile", func() {
+		appProject := generateAppProject(newArgoCDProject(main.ProjectSpec{
+			Environment:         "production",
+			EnvironmentProfiles: profiles,
+		}))
+		g.Expect(appProject.Spec.OrphanedResources).To(g.Equal(&argov1alpha1.OrphanedResourcesMonitorSettings{
+			Warn: &warn,
+		}))
+		g.Expect(appProject.Spec.SignatureKeys).To(g.Equal([]argov1alpha1.SignatureKey{
+			{KeyID: longKeyID},
+		}))
+	})
+
+	ginkgo.It("merges the project settings over the environment profile", func() {
+		appProject := generateAppProject(newArgoCDProject(main.ProjectSpec{
+			Environment:         "production",
+			EnvironmentProfiles: profiles,
+			OrphanedResources: &argov1alpha1.OrphanedResourcesMonitorSettings{
+				Ignore: []argov1alpha1.OrphanedResourceKey{
+					{Kind: "ConfigMap", Name: "kube-root-ca.crt"},
+				},
+			},
+			SignatureKeys: []string{
+				shortKeyID,
+				longKeyID,
+			},
+			AppProject: argov1alpha1.AppProject{
+				Spec: argov1alpha1.AppProjectSpec{
+					OrphanedResources: &argov1alpha1.OrphanedResourcesMonitorSettings{
+						Warn: &noWarn,
+					},
+				},
+			},
+		}))
+		g.Expect(appProject.Spec.OrphanedResources).To(g.Equal(&argov1alpha1.OrphanedResourcesMonitorSettings{
+			Warn: &noWarn,
+			Ignore: []argov1alpha1.OrphanedResourceKey{
+				{Kind: "ConfigMap", Name: "kube-root-ca.crt"},
+			},
+		}))
+		g.Expect(appProject.Spec.SignatureKeys).To(g.Equal([]argov1alpha1.SignatureKey{
+			{KeyID: shortKeyID},
+			{KeyID: longKeyID},
+		}))
+	})
+
+	ginkgo.DescribeTable("rejects", func(project main.ArgoCDProject, message string) {
+		data, err := yaml.Marshal(project)
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(g.ContainSubstring(message)))
+	},
+		ginkgo.Entry("key ID too short", newArgoCDProject(main.ProjectSpec{
+			Environment:         "staging",
+			EnvironmentProfiles: profiles,
+			SignatureKeys: []string{
+				"3AFDEB23",
+			},
+		}), `invalid signature key "3AFDEB23"`),
+		ginkgo.Entry("key ID not hexadecimal", newArgoCDProject(main.ProjectSpec{
+			Environment:         "staging",
+			EnvironmentProfiles: profiles,
+			SignatureKeys: []string{
+				"4AEE18F83AFDEBZZ",
+			},
+		}), `invalid signature key "4AEE18F83AFDEBZZ"`),
+		ginkgo.Entry("key ID in the AppProject template", newArgoCDProject(main.ProjectSpec{
+			Environment:         "staging",
+			EnvironmentProfiles: profiles,
+			AppProject: argov1alpha1.AppProject{
+				Spec: argov1alpha1.AppProjectSpec{
+					SignatureKeys: []argov1alpha1.SignatureKey{
+						{KeyID: "0x4AEE18F83AFDEB23"},
+					},
+				},
+			},
+		}), `invalid signature key "0x4AEE18F83AFDEB23"`),
+	)
+})
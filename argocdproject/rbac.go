@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+const (
+	separatorPolicy = ","
+
+	policyTypeGrouping = "g"
+)
+
+// validateProjectRoles checks the role policies of appProject with the same
+// validation Argo CD applies when roles are managed through its API. Grouping
+// policies, which Argo CD does not validate, must bind two roles of appProject.
+// builtinRoles maps the index of each role generated for a built-in access
+// level to that level, whose policies lead the role and are not checked, as
+// they grant access to every resource, which Argo CD does not allow project
+// roles to do.
+func validateProjectRoles(appProject *argov1alpha1.AppProject, builtinRoles map[int]accessLevel) error {
+	roleIndexes := make(map[string]int)
+	for i, role := range appProject.Spec.Roles {
+		if j, ok := roleIndexes[role.Name]; ok {
+			return fmt.Errorf("role %q at index %d is defined more than once, first at index %d", role.Name, i, j)
+		}
+		roleIndexes[role.Name] = i
+	}
+
+	var errs []error
+	for i, role := range appProject.Spec.Roles {
+		if accessLevel, ok := builtinRoles[i]; ok {
+			role.Policies = role.Policies[len(accessLevel.Policies(appProject.Name)):]
+		}
+		if err := validateProjectRole(appProject.Name, role, roleIndexes); err != nil {
+			errs = append(errs, fmt.Errorf("role %q at index %d: %w", role.Name, i, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func validateProjectRole(appProjectName string, role argov1alpha1.ProjectRole, roleIndexes map[string]int) error {
+	var errs []error
+
+	policies := make([]string, 0, len(role.Policies))
+	for _, policy := range role.Policies {
+		if isGroupingPolicy(policy) {
+			if err := validateGroupingPolicy(appProjectName, role.Name, policy, roleIndexes); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		policies = append(policies, policy)
+	}
+
+	role.Policies = policies
+	appProject := argov1alpha1.AppProject{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: appProjectName,
+		},
+		Spec: argov1alpha1.AppProjectSpec{
+			Roles: []argov1alpha1.ProjectRole{
+				role,
+			},
+		},
+	}
+	if err := appProject.ValidateProject(); err != nil {
+		errs = append(errs, errors.New(status.Convert(err).Message()))
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func isGroupingPolicy(policy string) bool {
+	policyType := strings.SplitN(policy, separatorPolicy, 2)[0]
+	return strings.TrimSpace(policyType) == policyTypeGrouping
+}
+
+func validateGroupingPolicy(appProjectName string, roleName string, policy string, roleIndexes map[string]int) error {
+	policyComponents := strings.Split(policy, separatorPolicy)
+	if len(policyComponents) != 3 {
+		return fmt.Errorf("invalid policy rule '%s': must be of the form: 'g, sub, role'", policy)
+	}
+
+	subject := strings.TrimSpace(policyComponents[1])
+	expectedSubject := fmt.Sprintf("proj:%s:%s", appProjectName, roleName)
+	if subject != expectedSubject {
+		return fmt.Errorf("invalid policy rule '%s': policy subject must be: '%s', not '%s'", policy, expectedSubject, subject)
+	}
+
+	inherited := strings.TrimSpace(policyComponents[2])
+	inheritedPrefix := fmt.Sprintf("proj:%s:", appProjectName)
+	if _, ok := roleIndexes[strings.TrimPrefix(inherited, inheritedPrefix)]; !strings.HasPrefix(inherited, inheritedPrefix) || !ok {
+		return fmt.Errorf("invalid policy rule '%s': inherited role must be one of the '%s' project roles, not '%s'", policy, appProjectName, inherited)
+	}
+
+	return nil
+}
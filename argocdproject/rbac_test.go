@@ -0,0 +1,135 @@
+package main_test
+
+import (
+	"bytes"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject RBAC validation", func() {
+	ginkgo.It("accepts well-formed roles", func() {
+		templateRole := argov1alpha1.ProjectRole{
+			Name: "deployer",
+			Policies: []string{
+				"p, proj:github-checker:deployer, applications, sync, github-checker/github-checker-app, allow",
+				"p, proj:github-checker:deployer, applications, action/*, github-checker/*, deny",
+				"g, proj:github-checker:deployer, proj:github-checker:read-only",
+			},
+			Groups: []string{
+				"sre:deployer",
+			},
+		}
+
+		appProject := generateAppProject(newArgoCDProject(main.ProjectSpec{
+			Environment: "staging",
+			AccessControl: main.AppProjectAccessControl{
+				Roles: []main.AccessControlRole{
+					main.AccessControlRole{
+						Name: "admin",
+						Actions: []string{
+							"delete",
+						},
+						Inherits: []string{
+							main.ReadSync.String(),
+						},
+					},
+				},
+			},
+			AppProject: argov1alpha1.AppProject{
+				Spec: argov1alpha1.AppProjectSpec{
+					Roles: []argov1alpha1.ProjectRole{
+						templateRole,
+					},
+				},
+			},
+		}))
+		g.Expect(appProject.Spec.Roles).To(g.ContainElement(templateRole))
+	})
+
+	ginkgo.DescribeTable("rejects", func(customRoles []main.AccessControlRole, templateRoles []argov1alpha1.ProjectRole, messages ...string) {
+		data, err := yaml.Marshal(newArgoCDProject(main.ProjectSpec{
+			Environment: "staging",
+			AccessControl: main.AppProjectAccessControl{
+				Roles: customRoles,
+			},
+			AppProject: argov1alpha1.AppProject{
+				Spec: argov1alpha1.AppProjectSpec{
+					Roles: templateRoles,
+				},
+			},
+		}))
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		err = main.GenerateManifests(data, &out)
+		g.Expect(err).To(g.HaveOccurred())
+		for _, message := range messages {
+			g.Expect(err.Error()).To(g.ContainSubstring(message))
+		}
+	},
+		ginkgo.Entry("policy targeting another project", nil, []argov1alpha1.ProjectRole{
+			argov1alpha1.ProjectRole{
+				Name: "deployer",
+				Policies: []string{
+					"p, proj:github-checker:deployer, applications, sync, another-checker/*, allow",
+				},
+			},
+		}, `role "deployer" at index 0: invalid policy rule`, "object must be of form 'github-checker/*'"),
+		ginkgo.Entry("policy for another role", nil, []argov1alpha1.ProjectRole{
+			argov1alpha1.ProjectRole{
+				Name: "deployer",
+				Policies: []string{
+					"p, proj:another-checker:deployer, applications, sync, github-checker/*, allow",
+				},
+			},
+		}, `role "deployer" at index 0: invalid policy rule`, "policy subject must be: 'proj:github-checker:deployer'"),
+		ginkgo.Entry("unknown action", []main.AccessControlRole{
+			main.AccessControlRole{
+				Name: "admin",
+				Actions: []string{
+					"destroy",
+				},
+			},
+		}, nil, `role "admin" at index 2: invalid policy rule`, "invalid action 'destroy'"),
+		ginkgo.Entry("grouping policy inheriting another project role", nil, []argov1alpha1.ProjectRole{
+			argov1alpha1.ProjectRole{
+				Name: "deployer",
+				Policies: []string{
+					"g, proj:github-checker:deployer, proj:another-checker:read-sync",
+				},
+			},
+		}, `role "deployer" at index 0: invalid policy rule`, "inherited role must be one of the 'github-checker' project roles"),
+		ginkgo.Entry("role copying a built-in policy of another role", nil, []argov1alpha1.ProjectRole{
+			argov1alpha1.ProjectRole{
+				Name: "deployer",
+				Policies: []string{
+					"p, proj:github-checker:read-only, *, get, github-checker/*, allow",
+				},
+			},
+		}, `role "deployer" at index 0: invalid policy rule`, "policy subject must be: 'proj:github-checker:deployer'"),
+		ginkgo.Entry("role redefining a generated role", nil, []argov1alpha1.ProjectRole{
+			argov1alpha1.ProjectRole{
+				Name: main.ReadOnly.String(),
+			},
+		}, `role "read-only" at index 1 is defined more than once, first at index 0`),
+		ginkgo.Entry("several invalid roles", nil, []argov1alpha1.ProjectRole{
+			argov1alpha1.ProjectRole{
+				Name: "deployer",
+				Policies: []string{
+					"p, proj:github-checker:deployer, applications, deploy, github-checker/*, allow",
+				},
+			},
+			argov1alpha1.ProjectRole{
+				Name: "viewer",
+				Groups: []string{
+					"sre, viewer",
+				},
+			},
+		}, `role "deployer" at index 0`, "invalid action 'deploy'", `role "viewer" at index 1`, "group 'sre, viewer' must be quoted"),
+	)
+})
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	rbacConfigMapName      = "argocd-rbac-cm"
+	rbacConfigMapNamespace = "argocd"
+
+	rbacConfigMapBehavior  = "merge"
+	rbacConfigMapNeedsHash = "false"
+
+	globalPolicyEffectAllow = "allow"
+	globalPolicyEffectDeny  = "deny"
+)
+
+func rbacPolicyKey(appProjectName string) string {
+	return fmt.Sprintf("policy.%s.csv", appProjectName)
+}
+
+// GlobalPolicy grants groups an action on Argo CD resources AppProject roles
+// cannot refer to, such as projects, ApplicationSets, certificates or the
+// applications of other projects.
+type GlobalPolicy struct {
+	Groups   []string `json:"groups"`
+	Resource string   `json:"resource"`
+	Action   string   `json:"action"`
+	Object   string   `json:"object"`
+	Effect   string   `json:"effect,omitempty"`
+}
+
+func (p GlobalPolicy) Policies() []string {
+	effect := p.Effect
+	if effect == "" {
+		effect = globalPolicyEffectAllow
+	}
+
+	policies := make([]string, 0, len(p.Groups))
+	for _, group := range p.Groups {
+		policies = append(policies, fmt.Sprintf("p, %s, %s, %s, %s, %s", group, p.Resource, p.Action, p.Object, effect))
+	}
+	return policies
+}
+
+func (p GlobalPolicy) validate() error {
+	if len(p.Groups) == 0 {
+		return errors.New("has no groups")
+	}
+	if p.Resource == "" || p.Action == "" || p.Object == "" {
+		return errors.New("must have a resource, an action and an object")
+	}
+	if p.Effect != "" && p.Effect != globalPolicyEffectAllow && p.Effect != globalPolicyEffectDeny {
+		return fmt.Errorf("effect must be %q or %q, not %q", globalPolicyEffectAllow, globalPolicyEffectDeny, p.Effect)
+	}
+	return nil
+}
+
+// GlobalPolicies returns the policies of the argocd-rbac-cm ConfigMap that
+// cannot be defined on the AppProject roles. Every group bound to the
+// read-only, read-sync or custom roles may get the project and its
+// ApplicationSets, followed by the policies of the global section.
+func (c AppProjectAccessControl) GlobalPolicies(appProjectName string) []string {
+	groups := append(append([]string{}, c.ReadOnly...), c.ReadSync...)
+	for _, role := range c.Roles {
+		groups = append(groups, role.Groups...)
+	}
+
+	var policies []string
+	seen := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		if seen[group] {
+			continue
+		}
+		seen[group] = true
+
+		policies = append(policies,
+			fmt.Sprintf("p, %[2]s, projects, get, %[1]s, allow", appProjectName, group),
+			fmt.Sprintf("p, %[2]s, applicationsets, get, %[1]s/*, allow", appProjectName, group),
+		)
+	}
+	for _, globalPolicy := range c.Global {
+		policies = append(policies, globalPolicy.Policies()...)
+	}
+	return policies
+}
+
+// makeRBACConfigMap returns a patch of the argocd-rbac-cm ConfigMap with the
+// global policies of every project, one policy.<project>.csv key each. It is
+// merged by Kustomize into the ConfigMap of the Argo CD installation, so
+// several ArgoCDProject generators can contribute to it.
+func makeRBACConfigMap(argocdProject *ArgoCDProject, environmentProjects []*ArgoCDProject) ([]byte, error) {
+	for i, globalPolicy := range argocdProject.Spec.AccessControl.Global {
+		if err := globalPolicy.validate(); err != nil {
+			return nil, fmt.Errorf("global policy %d: %w", i, err)
+		}
+	}
+
+	if !argocdProject.Spec.EmitRBACPolicy {
+		if len(argocdProject.Spec.AccessControl.Global) > 0 {
+			return nil, errors.New("global policies require emitRBACPolicy to be enabled")
+		}
+		return nil, nil
+	}
+
+	data := make(map[string]string, len(environmentProjects))
+	for _, environmentProject := range environmentProjects {
+		appProjectName := environmentProject.appProjectName()
+
+		var sb strings.Builder
+		for _, policy := range environmentProject.Spec.AccessControl.GlobalPolicies(appProjectName) {
+			sb.WriteString(policy)
+			sb.WriteString("\n")
+		}
+		data[rbacPolicyKey(appProjectName)] = sb.String()
+	}
+
+	configMap := corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: corev1.SchemeGroupVersion.String(),
+			Kind:       reflect.TypeOf(corev1.ConfigMap{}).Name(),
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      rbacConfigMapName,
+			Namespace: rbacConfigMapNamespace,
+			Annotations: map[string]string{
+				kustomizeBehaviorAnnotation:  rbacConfigMapBehavior,
+				kustomizeNeedsHashAnnotation: rbacConfigMapNeedsHash,
+			},
+		},
+		Data: data,
+	}
+
+	return marshalYAMLWithoutStatusField(configMap)
+}
@@ -0,0 +1,220 @@
+package main_test
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject RBAC ConfigMap", func() {
+	spec := main.ProjectSpec{
+		EmitRBACPolicy: true,
+		PropagateMetadata: main.MetadataPropagation{
+			Labels: true,
+		},
+		AccessControl: main.AppProjectAccessControl{
+			ReadOnly: []string{
+				"sre:eng-1",
+			},
+			ReadSync: []string{
+				"sre:eng-0",
+			},
+			Roles: []main.AccessControlRole{
+				main.AccessControlRole{
+					Name: "admin",
+					Groups: []string{
+						"sre:admin",
+					},
+					Actions: []string{
+						"delete",
+					},
+				},
+			},
+		},
+	}
+
+	generateConfigMaps := func(argoCDProject main.ArgoCDProject) []corev1.ConfigMap {
+		var configMaps []corev1.ConfigMap
+		for _, manifest := range generateManifests(argoCDProject) {
+			var meta metav1.TypeMeta
+			g.Expect(yaml.Unmarshal([]byte(manifest), &meta)).To(g.Succeed())
+
+			if meta.GroupVersionKind() == corev1.SchemeGroupVersion.WithKind("ConfigMap") {
+				var configMap corev1.ConfigMap
+				g.Expect(yaml.Unmarshal([]byte(manifest), &configMap)).To(g.Succeed())
+				configMaps = append(configMaps, configMap)
+			}
+		}
+		return configMaps
+	}
+
+	ginkgo.It("is not generated by default", func() {
+		project := newArgoCDProject(spec)
+		project.Spec.EmitRBACPolicy = false
+
+		g.Expect(generateConfigMaps(project)).To(g.BeEmpty())
+	})
+
+	ginkgo.It("patches argocd-rbac-cm with the global policies", func() {
+		project := newArgoCDProject(spec)
+		project.Labels = map[string]string{
+			"team": "sre",
+		}
+
+		configMaps := generateConfigMaps(project)
+		g.Expect(configMaps).To(g.HaveLen(1))
+
+		configMap := configMaps[0]
+		g.Expect(configMap.Name).To(g.Equal("argocd-rbac-cm"))
+		g.Expect(configMap.Namespace).To(g.Equal("argocd"))
+		g.Expect(configMap.Labels).To(g.BeEmpty())
+		g.Expect(configMap.Annotations).To(g.Equal(map[string]string{
+			"kustomize.config.k8s.io/behavior":   "merge",
+			"kustomize.config.k8s.io/needs-hash": "false",
+		}))
+		g.Expect(configMap.Data).To(g.Equal(map[string]string{
+			"policy.github-checker.csv": "" +
+				"p, sre:eng-1, projects, get, github-checker, allow\n" +
+				"p, sre:eng-1, applicationsets, get, github-checker/*, allow\n" +
+				"p, sre:eng-0, projects, get, github-checker, allow\n" +
+				"p, sre:eng-0, applicationsets, get, github-checker/*, allow\n" +
+				"p, sre:admin, projects, get, github-checker, allow\n" +
+				"p, sre:admin, applicationsets, get, github-checker/*, allow\n",
+		}))
+	})
+
+	ginkgo.It("appends the policies of the global section", func() {
+		project := newArgoCDProject(spec)
+		project.Spec.AccessControl.Global = []main.GlobalPolicy{
+			main.GlobalPolicy{
+				Groups: []string{
+					"sre:admin",
+					"sre:eng-0",
+				},
+				Resource: "applications",
+				Action:   "get",
+				Object:   "another-checker/*",
+			},
+			main.GlobalPolicy{
+				Groups: []string{
+					"sre:eng-1",
+				},
+				Resource: "certificates",
+				Action:   "*",
+				Object:   "*",
+				Effect:   "deny",
+			},
+		}
+
+		configMaps := generateConfigMaps(project)
+		g.Expect(configMaps).To(g.HaveLen(1))
+		g.Expect(configMaps[0].Data["policy.github-checker.csv"]).To(g.HaveSuffix("" +
+			"p, sre:admin, applications, get, another-checker/*, allow\n" +
+			"p, sre:eng-0, applications, get, another-checker/*, allow\n" +
+			"p, sre:eng-1, certificates, *, *, deny\n"))
+	})
+
+	ginkgo.It("only holds policies the AppProject roles do not define", func() {
+		project := newArgoCDProject(spec)
+		project.Spec.AccessControl.ReadSync = append(project.Spec.AccessControl.ReadSync, "sre:eng-1")
+
+		configMaps := generateConfigMaps(project)
+		g.Expect(configMaps).To(g.HaveLen(1))
+
+		var rolePolicies []string
+		for _, role := range generateAppProject(project).Spec.Roles {
+			rolePolicies = append(rolePolicies, role.Policies...)
+		}
+
+		policies := strings.Split(strings.TrimSuffix(configMaps[0].Data["policy.github-checker.csv"], "\n"), "\n")
+		g.Expect(policies).To(g.HaveLen(6))
+		for _, policy := range policies {
+			g.Expect(policy).NotTo(g.ContainSubstring("proj:"))
+			g.Expect(rolePolicies).NotTo(g.ContainElement(policy))
+		}
+	})
+
+	ginkgo.DescribeTable("rejects", func(emitRBACPolicy bool, globalPolicy main.GlobalPolicy, message string) {
+		project := newArgoCDProject(spec)
+		project.Spec.EmitRBACPolicy = emitRBACPolicy
+		project.Spec.AccessControl.Global = []main.GlobalPolicy{
+			globalPolicy,
+		}
+
+		data, err := yaml.Marshal(project)
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(message))
+	},
+		ginkgo.Entry("global policies without groups", true, main.GlobalPolicy{
+			Resource: "projects",
+			Action:   "get",
+			Object:   "*",
+		}, "global policy 0: has no groups"),
+		ginkgo.Entry("global policies without an object", true, main.GlobalPolicy{
+			Groups: []string{
+				"sre:admin",
+			},
+			Resource: "projects",
+			Action:   "get",
+		}, "global policy 0: must have a resource, an action and an object"),
+		ginkgo.Entry("global policies with an unknown effect", true, main.GlobalPolicy{
+			Groups: []string{
+				"sre:admin",
+			},
+			Resource: "projects",
+			Action:   "get",
+			Object:   "*",
+			Effect:   "grant",
+		}, `global policy 0: effect must be "allow" or "deny", not "grant"`),
+		ginkgo.Entry("global policies without emitting the RBAC policy", false, main.GlobalPolicy{
+			Groups: []string{
+				"sre:admin",
+			},
+			Resource: "projects",
+			Action:   "get",
+			Object:   "*",
+		}, "global policies require emitRBACPolicy to be enabled"),
+	)
+
+	ginkgo.It("has one policy per environment", func() {
+		project := newArgoCDProject(spec)
+		project.Spec.AccessControl.Roles = nil
+		project.Spec.Environments = []main.ProjectEnvironment{
+			main.ProjectEnvironment{
+				Name: "staging",
+				AccessControl: main.EnvironmentAccessControl{
+					ReadSync: []string{
+						"sre:eng-1",
+					},
+				},
+			},
+			main.ProjectEnvironment{
+				Name: "production",
+			},
+		}
+
+		configMaps := generateConfigMaps(project)
+		g.Expect(configMaps).To(g.HaveLen(1))
+		g.Expect(configMaps[0].Data).To(g.Equal(map[string]string{
+			"policy.github-checker-staging.csv": "" +
+				"p, sre:eng-1, projects, get, github-checker-staging, allow\n" +
+				"p, sre:eng-1, applicationsets, get, github-checker-staging/*, allow\n" +
+				"p, sre:eng-0, projects, get, github-checker-staging, allow\n" +
+				"p, sre:eng-0, applicationsets, get, github-checker-staging/*, allow\n",
+			"policy.github-checker-production.csv": "" +
+				"p, sre:eng-1, projects, get, github-checker-production, allow\n" +
+				"p, sre:eng-1, applicationsets, get, github-checker-production/*, allow\n" +
+				"p, sre:eng-0, projects, get, github-checker-production, allow\n" +
+				"p, sre:eng-0, applicationsets, get, github-checker-production/*, allow\n",
+		}))
+	})
+})
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	groupKindAll = "*"
+)
+
+type resourcePreset struct {
+	NamespaceResourceWhitelist []metav1.GroupKind
+	NamespaceResourceBlacklist []metav1.GroupKind
+	ClusterResourceWhitelist   []metav1.GroupKind
+	ClusterResourceBlacklist   []metav1.GroupKind
+}
+
+var builtinResourcePresets = map[string]resourcePreset{
+	"workloads-only": resourcePreset{
+		NamespaceResourceWhitelist: []metav1.GroupKind{
+			{Group: "", Kind: "ConfigMap"},
+			{Group: "", Kind: "PersistentVolumeClaim"},
+			{Group: "", Kind: "Pod"},
+			{Group: "", Kind: "Service"},
+			{Group: "", Kind: "ServiceAccount"},
+			{Group: "apps", Kind: "DaemonSet"},
+			{Group: "apps", Kind: "Deployment"},
+			{Group: "apps", Kind: "StatefulSet"},
+			{Group: "argoproj.io", Kind: "Rollout"},
+			{Group: "autoscaling", Kind: "HorizontalPodAutoscaler"},
+			{Group: "batch", Kind: "CronJob"},
+			{Group: "batch", Kind: "Job"},
+			{Group: "networking.k8s.io", Kind: "Ingress"},
+			{Group: "policy", Kind: "PodDisruptionBudget"},
+		},
+	},
+	"no-rbac": resourcePreset{
+		NamespaceResourceBlacklist: []metav1.GroupKind{
+			{Group: "rbac.authorization.k8s.io", Kind: "Role"},
+			{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"},
+		},
+		ClusterResourceBlacklist: []metav1.GroupKind{
+			{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"},
+			{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"},
+		},
+	},
+	"no-secrets": resourcePreset{
+		NamespaceResourceBlacklist: []metav1.GroupKind{
+			{Group: "", Kind: "Secret"},
+		},
+	},
+}
+
+// applyResourcePresets merges the resource lists of the project presets into
+// the ones of appProjectSpec. Entries already present are kept in place, so
+// the lists from spec.appProjectTemplate come first. When no namespace
+// resource whitelist ends up defined, every namespaced resource is allowed.
+// A preset restricting a whitelist that already allows every resource is
+// rejected, as the wildcard entry would override the restriction.
+func applyResourcePresets(argocdProject *ArgoCDProject, appProjectSpec *argov1alpha1.AppProjectSpec) error {
+	for _, name := range argocdProject.Spec.ResourcePresets {
+		preset, ok := builtinResourcePresets[name]
+		if !ok {
+			return fmt.Errorf("unknown resource preset %q", name)
+		}
+
+		if len(preset.NamespaceResourceWhitelist) > 0 && containsGroupKindAll(appProjectSpec.NamespaceResourceWhitelist) {
+			return fmt.Errorf("resource preset %q restricts a namespace resource whitelist allowing every resource", name)
+		}
+		if len(preset.ClusterResourceWhitelist) > 0 && containsGroupKindAll(appProjectSpec.ClusterResourceWhitelist) {
+			return fmt.Errorf("resource preset %q restricts a cluster resource whitelist allowing every resource", name)
+		}
+
+		appProjectSpec.NamespaceResourceWhitelist = mergeGroupKinds(appProjectSpec.NamespaceResourceWhitelist, preset.NamespaceResourceWhitelist)
+		appProjectSpec.NamespaceResourceBlacklist = mergeGroupKinds(appProjectSpec.NamespaceResourceBlacklist, preset.NamespaceResourceBlacklist)
+		appProjectSpec.ClusterResourceWhitelist = mergeGroupKinds(appProjectSpec.ClusterResourceWhitelist, preset.ClusterResourceWhitelist)
+		appProjectSpec.ClusterResourceBlacklist = mergeGroupKinds(appProjectSpec.ClusterResourceBlacklist, preset.ClusterResourceBlacklist)
+	}
+
+	if appProjectSpec.NamespaceResourceWhitelist == nil {
+		appProjectSpec.NamespaceResourceWhitelist = []metav1.GroupKind{
+			metav1.GroupKind{
+				Group: groupKindAll,
+				Kind:  groupKindAll,
+			},
+		}
+	}
+
+	return nil
+}
+
+func mergeGroupKinds(groupKinds []metav1.GroupKind, others []metav1.GroupKind) []metav1.GroupKind {
+	seen := make(map[metav1.GroupKind]bool, len(groupKinds))
+	for _, groupKind := range groupKinds {
+		seen[groupKind] = true
+	}
+
+	merged := groupKinds
+	for _, groupKind := range others {
+		if !seen[groupKind] {
+			seen[groupKind] = true
+			merged = append(merged, groupKind)
+		}
+	}
+	return merged
+}
+
+func containsGroupKindAll(groupKinds []metav1.GroupKind) bool {
+	for _, groupKind := range groupKinds {
+		if groupKind.Group == groupKindAll && groupKind.Kind == groupKindAll {
+			return true
+		}
+	}
+	return false
+}
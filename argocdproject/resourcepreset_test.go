@@ -0,0 +1,111 @@
+package main_test
+
+import (
+	"bytes"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject resource presets", func() {
+	ginkgo.DescribeTable("", func(templateSpec argov1alpha1.AppProjectSpec, presets []string, expected argov1alpha1.AppProjectSpec) {
+		appProject := generateAppProject(newArgoCDProject(main.ProjectSpec{
+			ResourcePresets: presets,
+			AppProject: argov1alpha1.AppProject{
+				Spec: templateSpec,
+			},
+		}))
+		g.Expect(appProject.Spec.NamespaceResourceWhitelist).To(g.Equal(expected.NamespaceResourceWhitelist))
+		g.Expect(appProject.Spec.NamespaceResourceBlacklist).To(g.Equal(expected.NamespaceResourceBlacklist))
+		g.Expect(appProject.Spec.ClusterResourceWhitelist).To(g.Equal(expected.ClusterResourceWhitelist))
+		g.Expect(appProject.Spec.ClusterResourceBlacklist).To(g.Equal(expected.ClusterResourceBlacklist))
+	},
+		ginkgo.Entry("allows every namespaced resource by default", argov1alpha1.AppProjectSpec{}, nil, argov1alpha1.AppProjectSpec{
+			NamespaceResourceWhitelist: []metav1.GroupKind{
+				{Group: "*", Kind: "*"},
+			},
+		}),
+		ginkgo.Entry("keeps the lists of the AppProject template", argov1alpha1.AppProjectSpec{
+			NamespaceResourceWhitelist: []metav1.GroupKind{
+				{Group: "apps", Kind: "Deployment"},
+			},
+			ClusterResourceWhitelist: []metav1.GroupKind{
+				{Group: "", Kind: "Namespace"},
+			},
+		}, nil, argov1alpha1.AppProjectSpec{
+			NamespaceResourceWhitelist: []metav1.GroupKind{
+				{Group: "apps", Kind: "Deployment"},
+			},
+			ClusterResourceWhitelist: []metav1.GroupKind{
+				{Group: "", Kind: "Namespace"},
+			},
+		}),
+		ginkgo.Entry("merges the presets into the lists of the AppProject template", argov1alpha1.AppProjectSpec{
+			NamespaceResourceBlacklist: []metav1.GroupKind{
+				{Group: "", Kind: "Secret"},
+				{Group: "", Kind: "ResourceQuota"},
+			},
+		}, []string{
+			"no-rbac",
+			"no-secrets",
+		}, argov1alpha1.AppProjectSpec{
+			NamespaceResourceWhitelist: []metav1.GroupKind{
+				{Group: "*", Kind: "*"},
+			},
+			NamespaceResourceBlacklist: []metav1.GroupKind{
+				{Group: "", Kind: "Secret"},
+				{Group: "", Kind: "ResourceQuota"},
+				{Group: "rbac.authorization.k8s.io", Kind: "Role"},
+				{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"},
+			},
+			ClusterResourceBlacklist: []metav1.GroupKind{
+				{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"},
+				{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"},
+			},
+		}),
+	)
+
+	ginkgo.It("restricts namespaced resources to workloads", func() {
+		appProject := generateAppProject(newArgoCDProject(main.ProjectSpec{
+			ResourcePresets: []string{
+				"workloads-only",
+			},
+		}))
+		g.Expect(appProject.Spec.NamespaceResourceWhitelist).To(g.ContainElements(
+			metav1.GroupKind{Group: "apps", Kind: "Deployment"},
+			metav1.GroupKind{Group: "", Kind: "Service"},
+		))
+		g.Expect(appProject.Spec.NamespaceResourceWhitelist).NotTo(g.ContainElement(metav1.GroupKind{Group: "*", Kind: "*"}))
+		g.Expect(appProject.Spec.NamespaceResourceWhitelist).NotTo(g.ContainElement(metav1.GroupKind{Group: "", Kind: "Secret"}))
+	})
+
+	ginkgo.DescribeTable("rejects", func(templateSpec argov1alpha1.AppProjectSpec, presets []string, message string) {
+		data, err := yaml.Marshal(newArgoCDProject(main.ProjectSpec{
+			ResourcePresets: presets,
+			AppProject: argov1alpha1.AppProject{
+				Spec: templateSpec,
+			},
+		}))
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(message))
+	},
+		ginkgo.Entry("unknown presets", argov1alpha1.AppProjectSpec{}, []string{
+			"no-crds",
+		}, `unknown resource preset "no-crds"`),
+		ginkgo.Entry("whitelist presets combined with a wildcard whitelist", argov1alpha1.AppProjectSpec{
+			NamespaceResourceWhitelist: []metav1.GroupKind{
+				{Group: "*", Kind: "*"},
+			},
+		}, []string{
+			"no-secrets",
+			"workloads-only",
+		}, `resource preset "workloads-only" restricts a namespace resource whitelist allowing every resource`),
+	)
+})
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/argoproj/argo-cd/v2/pkg/apis/application"
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	syncWaveAnnotation = "argocd.argoproj.io/sync-wave"
+
+	rootApplicationNamespace = "argocd"
+	rootApplicationProject   = "argocd"
+
+	rootApplicationSuffix = "-root"
+
+	appProjectSyncWave = -1
+)
+
+type RootApplication struct {
+	Name        string                               `json:"name,omitempty"`
+	Source      argov1alpha1.ApplicationSource       `json:"source"`
+	Destination *argov1alpha1.ApplicationDestination `json:"destination,omitempty"`
+	SyncPolicy  *argov1alpha1.SyncPolicy             `json:"syncPolicy,omitempty"`
+}
+
+// makeRootApplication returns the app-of-apps Application managing the
+// directory the ArgoCDProject generator lives in. It belongs to the argocd
+// project and namespace, and deploys to the cluster Argo CD runs in unless
+// another destination is set.
+func makeRootApplication(argocdProject *ArgoCDProject) ([]byte, error) {
+	rootApplication := argocdProject.Spec.RootApplication
+	if rootApplication == nil {
+		return nil, nil
+	}
+
+	if rootApplication.Source.RepoURL == "" {
+		return nil, fmt.Errorf("root application has no repoURL")
+	}
+	if rootApplication.Source.Path == "" {
+		return nil, fmt.Errorf("root application has no path")
+	}
+
+	name := rootApplication.Name
+	if name == "" {
+		name = argocdProject.Name + rootApplicationSuffix
+	}
+
+	destination := argov1alpha1.ApplicationDestination{
+		Server:    argov1alpha1.KubernetesInternalAPIServerAddr,
+		Namespace: rootApplicationNamespace,
+	}
+	if rootApplication.Destination != nil {
+		destination = *rootApplication.Destination
+	}
+
+	source := rootApplication.Source
+	app := argov1alpha1.Application{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: argov1alpha1.SchemeGroupVersion.String(),
+			Kind:       application.ApplicationKind,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: rootApplicationNamespace,
+		},
+		Spec: argov1alpha1.ApplicationSpec{
+			Project:     rootApplicationProject,
+			Source:      &source,
+			Destination: destination,
+			SyncPolicy:  rootApplication.SyncPolicy,
+		},
+	}
+
+	propagateMetadata(argocdProject, &app.ObjectMeta)
+
+	return marshalYAMLWithoutStatusField(app)
+}
+
+// applyRootApplicationSyncWave makes the root application create appProject
+// before the Applications that belong to it. A sync wave already set on the
+// AppProject template wins.
+func applyRootApplicationSyncWave(argocdProject *ArgoCDProject, appProject *argov1alpha1.AppProject) {
+	if argocdProject.Spec.RootApplication == nil {
+		return
+	}
+
+	if _, ok := appProject.Annotations[syncWaveAnnotation]; ok {
+		return
+	}
+	if appProject.Annotations == nil {
+		appProject.Annotations = make(map[string]string)
+	}
+	appProject.Annotations[syncWaveAnnotation] = strconv.Itoa(appProjectSyncWave)
+}
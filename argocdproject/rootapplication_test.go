@@ -0,0 +1,129 @@
+package main_test
+
+import (
+	"bytes"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject root application", func() {
+	appTemplates := []main.ApplicationTemplate{
+		newApplicationTemplate("github-checker", argov1alpha1.ApplicationDestination{}),
+	}
+
+	source := argov1alpha1.ApplicationSource{
+		RepoURL:        "https://github.com/t0rr3sp3dr0/argocd-bootstrap.git",
+		Path:           "./clusters/global-sre",
+		TargetRevision: "main",
+	}
+
+	findApplication := func(apps []argov1alpha1.Application, name string) *argov1alpha1.Application {
+		for i := range apps {
+			if apps[i].Name == name {
+				return &apps[i]
+			}
+		}
+		return nil
+	}
+
+	ginkgo.It("emits no root application by default", func() {
+		project := newArgoCDProject(main.ProjectSpec{
+			ApplicationTemplates: appTemplates,
+		})
+		g.Expect(generateApplications(project)).To(g.HaveLen(1))
+		g.Expect(generateAppProject(project).Annotations).To(g.BeEmpty())
+	})
+
+	ginkgo.It("emits a root application managing the generator directory", func() {
+		project := newArgoCDProject(main.ProjectSpec{
+			RootApplication: &main.RootApplication{
+				Source: source,
+			},
+			ApplicationTemplates: appTemplates,
+		})
+
+		apps := generateApplications(project)
+		g.Expect(apps).To(g.HaveLen(2))
+
+		rootApp := findApplication(apps, "github-checker-root")
+		g.Expect(rootApp).NotTo(g.BeNil())
+		g.Expect(rootApp.Namespace).To(g.Equal("argocd"))
+		g.Expect(rootApp.Spec.Project).To(g.Equal("argocd"))
+		g.Expect(rootApp.Spec.Source).To(g.Equal(&source))
+		g.Expect(rootApp.Spec.Destination).To(g.Equal(argov1alpha1.ApplicationDestination{
+			Server:    "https://kubernetes.default.svc",
+			Namespace: "argocd",
+		}))
+
+		g.Expect(generateAppProject(project).Annotations).To(g.Equal(map[string]string{
+			"argocd.argoproj.io/sync-wave": "-1",
+		}))
+	})
+
+	ginkgo.It("honors the name, destination and sync policy of the root application", func() {
+		syncPolicy := &argov1alpha1.SyncPolicy{
+			Automated: &argov1alpha1.SyncPolicyAutomated{
+				Prune: true,
+			},
+		}
+		project := newArgoCDProject(main.ProjectSpec{
+			RootApplication: &main.RootApplication{
+				Name:   "global-sre",
+				Source: source,
+				Destination: &argov1alpha1.ApplicationDestination{
+					Name:      "Global-SRE",
+					Namespace: "argocd",
+				},
+				SyncPolicy: syncPolicy,
+			},
+			AppProject: argov1alpha1.AppProject{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{
+						"argocd.argoproj.io/sync-wave": "-5",
+					},
+				},
+			},
+			ApplicationTemplates: appTemplates,
+		})
+
+		rootApp := findApplication(generateApplications(project), "global-sre")
+		g.Expect(rootApp).NotTo(g.BeNil())
+		g.Expect(rootApp.Spec.Destination).To(g.Equal(argov1alpha1.ApplicationDestination{
+			Name:      "Global-SRE",
+			Namespace: "argocd",
+		}))
+		g.Expect(rootApp.Spec.SyncPolicy).To(g.Equal(syncPolicy))
+
+		g.Expect(generateAppProject(project).Annotations).To(g.Equal(map[string]string{
+			"argocd.argoproj.io/sync-wave": "-5",
+		}))
+	})
+
+	ginkgo.DescribeTable("rejects", func(rootApplication main.RootApplication, message string) {
+		data, err := yaml.Marshal(newArgoCDProject(main.ProjectSpec{
+			RootApplication:      &rootApplication,
+			ApplicationTemplates: appTemplates,
+		}))
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(message))
+	},
+		ginkgo.Entry("root application without repoURL", main.RootApplication{
+			Source: argov1alpha1.ApplicationSource{
+				Path: "./clusters/global-sre",
+			},
+		}, "root application has no repoURL"),
+		ginkgo.Entry("root application without path", main.RootApplication{
+			Source: argov1alpha1.ApplicationSource{
+				RepoURL: "https://github.com/t0rr3sp3dr0/argocd-bootstrap.git",
+			},
+		}, "root application has no path"),
+	)
+})
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+)
+
+const (
+	separatorKustomizeImageOverride = "="
+	separatorKustomizeImageDigest   = "@"
+	separatorKustomizeImageTag      = ":"
+	separatorKustomizeImagePath     = "/"
+)
+
+type HelmShorthand struct {
+	ValueFiles []string                     `json:"valueFiles,omitempty"`
+	Parameters []argov1alpha1.HelmParameter `json:"parameters,omitempty"`
+}
+
+type KustomizeShorthand struct {
+	Images []string `json:"images,omitempty"`
+}
+
+// applySourceShorthands expands the Helm and Kustomize shorthands of
+// appTemplate into the sources of its application of the same type, since
+// Argo CD rejects sources declaring more than one. The Helm shorthand applies
+// to chart sources and sources with Helm options, or to the only source when
+// it declares no type, while the Kustomize shorthand applies to path sources
+// not declared as another type. Value files, parameter values and images are
+// Go templates with the same data as the conventions. Parameters and images
+// already defined on a source win.
+func applySourceShorthands(appTemplate *ApplicationTemplate, data conventionData) error {
+	if appTemplate.Helm == nil && appTemplate.Kustomize == nil {
+		return nil
+	}
+	if appTemplate.Helm != nil && appTemplate.Kustomize != nil {
+		return fmt.Errorf("application %q cannot use both helm and kustomize shorthands", data.Application)
+	}
+
+	helm, err := interpolateHelmShorthand(appTemplate.Helm, data)
+	if err != nil {
+		return err
+	}
+
+	kustomize, err := interpolateKustomizeShorthand(appTemplate.Kustomize, data)
+	if err != nil {
+		return err
+	}
+
+	app := &appTemplate.Application
+
+	if helm != nil {
+		multipleSources := app.Spec.HasMultipleSources()
+		sources := applicationSources(app, func(source *argov1alpha1.ApplicationSource) bool {
+			return isHelmSource(source) || (!multipleSources && isUntypedSource(source))
+		})
+		if len(sources) == 0 {
+			return fmt.Errorf("application %q has no helm source for the helm shorthand", data.Application)
+		}
+
+		for _, source := range sources {
+			mergeHelmShorthand(source, helm)
+		}
+	}
+
+	if kustomize != nil {
+		sources := applicationSources(app, isKustomizeSource)
+		if len(sources) == 0 {
+			return fmt.Errorf("application %q has no kustomize source for the kustomize shorthand", data.Application)
+		}
+
+		for _, source := range sources {
+			mergeKustomizeShorthand(source, kustomize)
+		}
+	}
+
+	return nil
+}
+
+// isHelmSource reports whether source is a Helm chart, either from a Helm
+// repository or declaring Helm options.
+func isHelmSource(source *argov1alpha1.ApplicationSource) bool {
+	return source.Chart != "" || source.Helm != nil
+}
+
+// isKustomizeSource reports whether source is a path that is not declared as
+// any other type, so Kustomize options can be added to it.
+func isKustomizeSource(source *argov1alpha1.ApplicationSource) bool {
+	return source.Chart == "" && source.Helm == nil && source.Directory == nil && source.Plugin == nil
+}
+
+// isUntypedSource reports whether source declares no type, leaving Argo CD to
+// detect it from the files in its path.
+func isUntypedSource(source *argov1alpha1.ApplicationSource) bool {
+	return isKustomizeSource(source) && source.Kustomize == nil
+}
+
+func interpolateHelmShorthand(helm *HelmShorthand, data conventionData) (*HelmShorthand, error) {
+	if helm == nil {
+		return nil, nil
+	}
+
+	interpolated := &HelmShorthand{
+		ValueFiles: make([]string, 0, len(helm.ValueFiles)),
+		Parameters: make([]argov1alpha1.HelmParameter, 0, len(helm.Parameters)),
+	}
+
+	for _, valueFile := range helm.ValueFiles {
+		s, err := interpolateShorthand("helm value file", valueFile, data)
+		if err != nil {
+			return nil, err
+		}
+		interpolated.ValueFiles = append(interpolated.ValueFiles, s)
+	}
+
+	for _, parameter := range helm.Parameters {
+		if parameter.Name == "" {
+			return nil, fmt.Errorf("application %q: helm parameter has no name", data.Application)
+		}
+
+		s, err := interpolateShorthand("helm parameter", parameter.Value, data)
+		if err != nil {
+			return nil, err
+		}
+		parameter.Value = s
+		interpolated.Parameters = append(interpolated.Parameters, parameter)
+	}
+
+	return interpolated, nil
+}
+
+func interpolateKustomizeShorthand(kustomize *KustomizeShorthand, data conventionData) (*KustomizeShorthand, error) {
+	if kustomize == nil {
+		return nil, nil
+	}
+
+	interpolated := &KustomizeShorthand{
+		Images: make([]string, 0, len(kustomize.Images)),
+	}
+
+	for _, image := range kustomize.Images {
+		s, err := interpolateShorthand("kustomize image", image, data)
+		if err != nil {
+			return nil, err
+		}
+		interpolated.Images = append(interpolated.Images, s)
+	}
+
+	return interpolated, nil
+}
+
+func interpolateShorthand(name string, text string, data conventionData) (string, error) {
+	tmpl, err := template.New(name).Option(tmplOption).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("application %q: invalid %s %q: %w", data.Application, name, text, err)
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("application %q: invalid %s %q: %w", data.Application, name, text, err)
+	}
+	return sb.String(), nil
+}
+
+func mergeHelmShorthand(source *argov1alpha1.ApplicationSource, helm *HelmShorthand) {
+	if source.Helm == nil {
+		source.Helm = &argov1alpha1.ApplicationSourceHelm{}
+	}
+
+	seen := make(map[string]bool, len(source.Helm.ValueFiles))
+	for _, valueFile := range source.Helm.ValueFiles {
+		seen[valueFile] = true
+	}
+	for _, valueFile := range helm.ValueFiles {
+		if !seen[valueFile] {
+			seen[valueFile] = true
+			source.Helm.ValueFiles = append(source.Helm.ValueFiles, valueFile)
+		}
+	}
+
+	defined := make(map[string]bool, len(source.Helm.Parameters))
+	for _, parameter := range source.Helm.Parameters {
+		defined[parameter.Name] = true
+	}
+	for _, parameter := range helm.Parameters {
+		if !defined[parameter.Name] {
+			source.Helm.AddParameter(parameter)
+		}
+	}
+}
+
+func mergeKustomizeShorthand(source *argov1alpha1.ApplicationSource, kustomize *KustomizeShorthand) {
+	if source.Kustomize == nil {
+		source.Kustomize = &argov1alpha1.ApplicationSourceKustomize{}
+	}
+
+	defined := make(map[string]bool, len(source.Kustomize.Images))
+	for _, image := range source.Kustomize.Images {
+		defined[kustomizeImageName(string(image))] = true
+	}
+	for _, image := range kustomize.Images {
+		if name := kustomizeImageName(image); !defined[name] {
+			defined[name] = true
+			source.Kustomize.Images = append(source.Kustomize.Images, argov1alpha1.KustomizeImage(image))
+		}
+	}
+}
+
+// kustomizeImageName returns the name of the image overridden by a Kustomize
+// image formatted as [<old_image_name>=]<image_name>[:<image_tag>|@<digest>].
+// Unlike argov1alpha1.KustomizeImage.Match, image names sharing a prefix are
+// told apart.
+func kustomizeImageName(image string) string {
+	if i := strings.Index(image, separatorKustomizeImageOverride); i >= 0 {
+		return image[:i]
+	}
+	if i := strings.Index(image, separatorKustomizeImageDigest); i >= 0 {
+		image = image[:i]
+	}
+	if i := strings.LastIndex(image, separatorKustomizeImageTag); i > strings.LastIndex(image, separatorKustomizeImagePath) {
+		image = image[:i]
+	}
+	return image
+}
@@ -0,0 +1,304 @@
+package main_test
+
+import (
+	"bytes"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject source shorthands", func() {
+	source := func() *argov1alpha1.ApplicationSource {
+		return &argov1alpha1.ApplicationSource{
+			RepoURL: "https://github.com/t0rr3sp3dr0/github-checker.git",
+		}
+	}
+
+	ginkgo.It("expands the helm shorthand with environment interpolation", func() {
+		apps := generateApplications(newArgoCDProject(main.ProjectSpec{
+			Environment: "production",
+			ApplicationTemplates: []main.ApplicationTemplate{
+				main.ApplicationTemplate{
+					Application: argov1alpha1.Application{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "github-checker",
+						},
+						Spec: argov1alpha1.ApplicationSpec{
+							Source: source(),
+						},
+					},
+					Helm: &main.HelmShorthand{
+						ValueFiles: []string{
+							"values.yaml",
+							"values-{{ .Environment }}.yaml",
+						},
+						Parameters: []argov1alpha1.HelmParameter{
+							{Name: "image.tag", Value: "{{ .Environment }}"},
+							{Name: "fullnameOverride", Value: "{{ .Application }}"},
+						},
+					},
+				},
+			},
+		}))
+		g.Expect(apps).To(g.HaveLen(1))
+		g.Expect(apps[0].Spec.Source.Helm).To(g.Equal(&argov1alpha1.ApplicationSourceHelm{
+			ValueFiles: []string{
+				"values.yaml",
+				"values-production.yaml",
+			},
+			Parameters: []argov1alpha1.HelmParameter{
+				{Name: "image.tag", Value: "production"},
+				{Name: "fullnameOverride", Value: "github-checker"},
+			},
+		}))
+	})
+
+	ginkgo.It("lets sources override the helm shorthand", func() {
+		appSource := source()
+		appSource.Helm = &argov1alpha1.ApplicationSourceHelm{
+			ValueFiles: []string{
+				"values-production.yaml",
+			},
+			Parameters: []argov1alpha1.HelmParameter{
+				{Name: "image.tag", Value: "v1.2.3"},
+			},
+		}
+
+		apps := generateApplications(newArgoCDProject(main.ProjectSpec{
+			Environment: "production",
+			ApplicationTemplates: []main.ApplicationTemplate{
+				main.ApplicationTemplate{
+					Application: argov1alpha1.Application{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "github-checker",
+						},
+						Spec: argov1alpha1.ApplicationSpec{
+							Source: appSource,
+						},
+					},
+					Helm: &main.HelmShorthand{
+						ValueFiles: []string{
+							"values.yaml",
+							"values-{{ .Environment }}.yaml",
+						},
+						Parameters: []argov1alpha1.HelmParameter{
+							{Name: "image.tag", Value: "{{ .Environment }}"},
+						},
+					},
+				},
+			},
+		}))
+		g.Expect(apps).To(g.HaveLen(1))
+		g.Expect(apps[0].Spec.Source.Helm).To(g.Equal(&argov1alpha1.ApplicationSourceHelm{
+			ValueFiles: []string{
+				"values-production.yaml",
+				"values.yaml",
+			},
+			Parameters: []argov1alpha1.HelmParameter{
+				{Name: "image.tag", Value: "v1.2.3"},
+			},
+		}))
+	})
+
+	ginkgo.It("expands the kustomize shorthand on every source but refs", func() {
+		apps := generateApplications(newArgoCDProject(main.ProjectSpec{
+			Environment: "production",
+			ApplicationTemplates: []main.ApplicationTemplate{
+				main.ApplicationTemplate{
+					Application: argov1alpha1.Application{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "github-checker",
+						},
+						Spec: argov1alpha1.ApplicationSpec{
+							Sources: argov1alpha1.ApplicationSources{
+								argov1alpha1.ApplicationSource{
+									RepoURL: "https://github.com/t0rr3sp3dr0/github-checker.git",
+									Kustomize: &argov1alpha1.ApplicationSourceKustomize{
+										Images: argov1alpha1.KustomizeImages{
+											"ghcr.io/t0rr3sp3dr0/github-checker-worker:v1.2.3",
+										},
+									},
+								},
+								argov1alpha1.ApplicationSource{
+									RepoURL: "https://github.com/t0rr3sp3dr0/github-checker-values.git",
+									Ref:     "values",
+								},
+							},
+						},
+					},
+					Kustomize: &main.KustomizeShorthand{
+						Images: []string{
+							"ghcr.io/t0rr3sp3dr0/github-checker:{{ .Environment }}",
+							"ghcr.io/t0rr3sp3dr0/github-checker-worker:{{ .Environment }}",
+						},
+					},
+				},
+			},
+		}))
+		g.Expect(apps).To(g.HaveLen(1))
+		g.Expect(apps[0].Spec.Sources[0].Kustomize.Images).To(g.Equal(argov1alpha1.KustomizeImages{
+			"ghcr.io/t0rr3sp3dr0/github-checker-worker:v1.2.3",
+			"ghcr.io/t0rr3sp3dr0/github-checker:production",
+		}))
+		g.Expect(apps[0].Spec.Sources[1].Kustomize).To(g.BeNil())
+	})
+
+	multiSource := func() argov1alpha1.Application {
+		return argov1alpha1.Application{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "github-checker",
+			},
+			Spec: argov1alpha1.ApplicationSpec{
+				Sources: argov1alpha1.ApplicationSources{
+					argov1alpha1.ApplicationSource{
+						RepoURL:        "https://charts.bitnami.com/bitnami",
+						Chart:          "redis",
+						TargetRevision: "17.11.3",
+						Helm: &argov1alpha1.ApplicationSourceHelm{
+							ReleaseName: "redis",
+						},
+					},
+					argov1alpha1.ApplicationSource{
+						RepoURL: "https://github.com/t0rr3sp3dr0/github-checker.git",
+					},
+					argov1alpha1.ApplicationSource{
+						RepoURL: "https://github.com/t0rr3sp3dr0/github-checker-manifests.git",
+						Directory: &argov1alpha1.ApplicationSourceDirectory{
+							Recurse: true,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	ginkgo.It("expands the helm shorthand on helm sources only", func() {
+		apps := generateApplications(newArgoCDProject(main.ProjectSpec{
+			Environment: "production",
+			ApplicationTemplates: []main.ApplicationTemplate{
+				main.ApplicationTemplate{
+					Application: multiSource(),
+					Helm: &main.HelmShorthand{
+						ValueFiles: []string{
+							"values-{{ .Environment }}.yaml",
+						},
+					},
+				},
+			},
+		}))
+		g.Expect(apps).To(g.HaveLen(1))
+		g.Expect(apps[0].Spec.Sources[0].Helm).To(g.Equal(&argov1alpha1.ApplicationSourceHelm{
+			ReleaseName: "redis",
+			ValueFiles: []string{
+				"values-production.yaml",
+			},
+		}))
+		g.Expect(apps[0].Spec.Sources[0].Kustomize).To(g.BeNil())
+		g.Expect(apps[0].Spec.Sources[1].Helm).To(g.BeNil())
+		g.Expect(apps[0].Spec.Sources[2].Helm).To(g.BeNil())
+	})
+
+	ginkgo.It("expands the kustomize shorthand on kustomize sources only", func() {
+		apps := generateApplications(newArgoCDProject(main.ProjectSpec{
+			Environment: "production",
+			ApplicationTemplates: []main.ApplicationTemplate{
+				main.ApplicationTemplate{
+					Application: multiSource(),
+					Kustomize: &main.KustomizeShorthand{
+						Images: []string{
+							"ghcr.io/t0rr3sp3dr0/github-checker:{{ .Environment }}",
+						},
+					},
+				},
+			},
+		}))
+		g.Expect(apps).To(g.HaveLen(1))
+		g.Expect(apps[0].Spec.Sources[0].Kustomize).To(g.BeNil())
+		g.Expect(apps[0].Spec.Sources[1].Kustomize).To(g.Equal(&argov1alpha1.ApplicationSourceKustomize{
+			Images: argov1alpha1.KustomizeImages{
+				"ghcr.io/t0rr3sp3dr0/github-checker:production",
+			},
+		}))
+		g.Expect(apps[0].Spec.Sources[2].Kustomize).To(g.BeNil())
+	})
+
+	ginkgo.DescribeTable("rejects", func(appTemplate main.ApplicationTemplate, message string) {
+		appTemplate.Name = "github-checker"
+		if appTemplate.Spec.Source == nil {
+			appTemplate.Spec.Source = source()
+		}
+		data, err := yaml.Marshal(newArgoCDProject(main.ProjectSpec{
+			Environment: "production",
+			ApplicationTemplates: []main.ApplicationTemplate{
+				appTemplate,
+			},
+		}))
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(g.ContainSubstring(message)))
+	},
+		ginkgo.Entry("both shorthands", main.ApplicationTemplate{
+			Helm:      &main.HelmShorthand{},
+			Kustomize: &main.KustomizeShorthand{},
+		}, `application "github-checker" cannot use both helm and kustomize shorthands`),
+		ginkgo.Entry("helm parameter without name", main.ApplicationTemplate{
+			Helm: &main.HelmShorthand{
+				Parameters: []argov1alpha1.HelmParameter{
+					{Value: "production"},
+				},
+			},
+		}, `application "github-checker": helm parameter has no name`),
+		ginkgo.Entry("unknown template field", main.ApplicationTemplate{
+			Helm: &main.HelmShorthand{
+				ValueFiles: []string{
+					"values-{{ .Env }}.yaml",
+				},
+			},
+		}, `application "github-checker": invalid helm value file "values-{{ .Env }}.yaml"`),
+		ginkgo.Entry("malformed template", main.ApplicationTemplate{
+			Kustomize: &main.KustomizeShorthand{
+				Images: []string{
+					"ghcr.io/t0rr3sp3dr0/github-checker:{{ .Environment",
+				},
+			},
+		}, `application "github-checker": invalid kustomize image`),
+		ginkgo.Entry("helm shorthand without helm sources", main.ApplicationTemplate{
+			Application: argov1alpha1.Application{
+				Spec: argov1alpha1.ApplicationSpec{
+					Source: &argov1alpha1.ApplicationSource{
+						RepoURL: "https://github.com/t0rr3sp3dr0/github-checker.git",
+						Kustomize: &argov1alpha1.ApplicationSourceKustomize{
+							NamePrefix: "prod-",
+						},
+					},
+				},
+			},
+			Helm: &main.HelmShorthand{
+				ValueFiles: []string{
+					"values.yaml",
+				},
+			},
+		}, `application "github-checker" has no helm source for the helm shorthand`),
+		ginkgo.Entry("kustomize shorthand without kustomize sources", main.ApplicationTemplate{
+			Application: argov1alpha1.Application{
+				Spec: argov1alpha1.ApplicationSpec{
+					Source: &argov1alpha1.ApplicationSource{
+						RepoURL: "https://charts.bitnami.com/bitnami",
+						Chart:   "redis",
+					},
+				},
+			},
+			Kustomize: &main.KustomizeShorthand{
+				Images: []string{
+					"ghcr.io/t0rr3sp3dr0/github-checker:v1.2.3",
+				},
+			},
+		}, `application "github-checker" has no kustomize source for the kustomize shorthand`),
+	)
+})
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+)
+
+const (
+	separatorCron      = " "
+	separatorCronList  = ","
+	separatorClockTime = ":"
+
+	cronAny = "*"
+
+	defaultWindowStart = "00:00"
+	defaultWindowEnd   = "24:00"
+
+	applicationAll = "*"
+)
+
+var (
+	clockTimeRegexp = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$|^24:00$`)
+
+	weekdays = map[string][]time.Weekday{
+		"sun":       {time.Sunday},
+		"sunday":    {time.Sunday},
+		"mon":       {time.Monday},
+		"monday":    {time.Monday},
+		"tue":       {time.Tuesday},
+		"tuesday":   {time.Tuesday},
+		"wed":       {time.Wednesday},
+		"wednesday": {time.Wednesday},
+		"thu":       {time.Thursday},
+		"thursday":  {time.Thursday},
+		"fri":       {time.Friday},
+		"friday":    {time.Friday},
+		"sat":       {time.Saturday},
+		"saturday":  {time.Saturday},
+		"weekdays":  {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		"weekends":  {time.Saturday, time.Sunday},
+	}
+)
+
+type MaintenanceWindow struct {
+	Kind         string   `json:"kind"`
+	Days         []string `json:"days,omitempty"`
+	Start        string   `json:"start,omitempty"`
+	End          string   `json:"end,omitempty"`
+	Schedule     string   `json:"schedule,omitempty"`
+	Duration     string   `json:"duration,omitempty"`
+	TimeZone     string   `json:"timeZone,omitempty"`
+	Environments []string `json:"environments,omitempty"`
+	Applications []string `json:"applications,omitempty"`
+	Namespaces   []string `json:"namespaces,omitempty"`
+	Clusters     []string `json:"clusters,omitempty"`
+	ManualSync   bool     `json:"manualSync,omitempty"`
+}
+
+func (w *MaintenanceWindow) appliesTo(environment string) bool {
+	if len(w.Environments) == 0 {
+		return true
+	}
+
+	for _, e := range w.Environments {
+		if e == environment {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *MaintenanceWindow) SyncWindow() (*argov1alpha1.SyncWindow, error) {
+	schedule, duration, err := w.scheduleAndDuration()
+	if err != nil {
+		return nil, err
+	}
+
+	if w.TimeZone != "" {
+		if _, err := time.LoadLocation(w.TimeZone); err != nil {
+			return nil, fmt.Errorf("invalid time zone %q: %w", w.TimeZone, err)
+		}
+	}
+
+	syncWindow := &argov1alpha1.SyncWindow{
+		Kind:         w.Kind,
+		Schedule:     schedule,
+		Duration:     duration,
+		Applications: w.Applications,
+		Namespaces:   w.Namespaces,
+		Clusters:     w.Clusters,
+		ManualSync:   w.ManualSync,
+		TimeZone:     w.TimeZone,
+	}
+	if len(syncWindow.Applications) == 0 && len(syncWindow.Namespaces) == 0 && len(syncWindow.Clusters) == 0 {
+		syncWindow.Applications = []string{
+			applicationAll,
+		}
+	}
+
+	// Validate defaults the time zone of the window it validates, which would
+	// add timeZone: UTC to every window without one.
+	validated := *syncWindow
+	if err := validated.Validate(); err != nil {
+		return nil, err
+	}
+	return syncWindow, nil
+}
+
+func (w *MaintenanceWindow) scheduleAndDuration() (string, string, error) {
+	if w.Schedule != "" {
+		if len(w.Days) != 0 || w.Start != "" || w.End != "" {
+			return "", "", fmt.Errorf("schedule cannot be combined with days, start or end")
+		}
+		if w.Duration == "" {
+			return "", "", fmt.Errorf("schedule requires a duration")
+		}
+		return w.Schedule, w.Duration, nil
+	}
+
+	if w.Duration != "" {
+		return "", "", fmt.Errorf("duration requires a schedule, use start and end instead")
+	}
+
+	start, err := parseClockTime(w.Start, defaultWindowStart)
+	if err != nil {
+		return "", "", err
+	}
+	if start == 24*time.Hour {
+		return "", "", fmt.Errorf("invalid start %q", w.Start)
+	}
+
+	end, err := parseClockTime(w.End, defaultWindowEnd)
+	if err != nil {
+		return "", "", err
+	}
+
+	duration := end - start
+	if duration <= 0 {
+		duration += 24 * time.Hour
+	}
+
+	daysOfWeek, err := parseDaysOfWeek(w.Days)
+	if err != nil {
+		return "", "", err
+	}
+
+	schedule := strings.Join([]string{
+		strconv.Itoa(int(start.Minutes()) % 60),
+		strconv.Itoa(int(start.Hours())),
+		cronAny,
+		cronAny,
+		daysOfWeek,
+	}, separatorCron)
+
+	return schedule, formatDuration(duration), nil
+}
+
+func parseClockTime(s string, defaultValue string) (time.Duration, error) {
+	if s == "" {
+		s = defaultValue
+	}
+
+	if !clockTimeRegexp.MatchString(s) {
+		return 0, fmt.Errorf("invalid time %q: must be formatted as HH:MM", s)
+	}
+
+	parts := strings.SplitN(s, separatorClockTime, 2)
+	hours, _ := strconv.Atoi(parts[0])
+	minutes, _ := strconv.Atoi(parts[1])
+
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute, nil
+}
+
+func parseDaysOfWeek(days []string) (string, error) {
+	if len(days) == 0 {
+		return cronAny, nil
+	}
+
+	seen := make(map[time.Weekday]bool)
+	for _, day := range days {
+		dayWeekdays, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return "", fmt.Errorf("invalid day %q", day)
+		}
+
+		for _, weekday := range dayWeekdays {
+			seen[weekday] = true
+		}
+	}
+
+	daysOfWeek := make([]string, 0, len(seen))
+	for weekday := range seen {
+		daysOfWeek = append(daysOfWeek, strconv.Itoa(int(weekday)))
+	}
+	sort.Strings(daysOfWeek)
+
+	return strings.Join(daysOfWeek, separatorCronList), nil
+}
+
+func formatDuration(d time.Duration) string {
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+
+	switch {
+	case minutes == 0:
+		return fmt.Sprintf("%dh", hours)
+	case hours == 0:
+		return fmt.Sprintf("%dm", minutes)
+	default:
+		return fmt.Sprintf("%dh%dm", hours, minutes)
+	}
+}
+
+func makeSyncWindows(argocdProject *ArgoCDProject) (argov1alpha1.SyncWindows, error) {
+	var syncWindows argov1alpha1.SyncWindows
+	for i := range argocdProject.Spec.MaintenanceWindows {
+		maintenanceWindow := &argocdProject.Spec.MaintenanceWindows[i]
+		if !maintenanceWindow.appliesTo(argocdProject.Spec.Environment) {
+			continue
+		}
+
+		syncWindow, err := maintenanceWindow.SyncWindow()
+		if err != nil {
+			return nil, fmt.Errorf("maintenance window %d: %w", i, err)
+		}
+		syncWindows = append(syncWindows, syncWindow)
+	}
+	return syncWindows, nil
+}
@@ -0,0 +1,184 @@
+package main_test
+
+import (
+	"bytes"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject maintenance windows", func() {
+	ginkgo.DescribeTable("translates", func(maintenanceWindow main.MaintenanceWindow, expected argov1alpha1.SyncWindow) {
+		appProject := generateAppProject(newArgoCDProject(main.ProjectSpec{
+			Environment: "production",
+			MaintenanceWindows: []main.MaintenanceWindow{
+				maintenanceWindow,
+			},
+		}))
+		g.Expect(appProject.Spec.SyncWindows).To(g.Equal(argov1alpha1.SyncWindows{
+			&expected,
+		}))
+	},
+		ginkgo.Entry("weekends", main.MaintenanceWindow{
+			Kind: "deny",
+			Days: []string{
+				"weekends",
+			},
+		}, argov1alpha1.SyncWindow{
+			Kind:         "deny",
+			Schedule:     "0 0 * * 0,6",
+			Duration:     "24h",
+			Applications: []string{"*"},
+		}),
+		ginkgo.Entry("daily time range", main.MaintenanceWindow{
+			Kind:     "allow",
+			Start:    "02:00",
+			End:      "04:30",
+			TimeZone: "UTC",
+			Namespaces: []string{
+				"github-checker",
+			},
+		}, argov1alpha1.SyncWindow{
+			Kind:       "allow",
+			Schedule:   "0 2 * * *",
+			Duration:   "2h30m",
+			Namespaces: []string{"github-checker"},
+			TimeZone:   "UTC",
+		}),
+		ginkgo.Entry("overnight time range on specific days", main.MaintenanceWindow{
+			Kind:  "deny",
+			Days:  []string{"Fri", "monday"},
+			Start: "22:15",
+			End:   "02:00",
+			Clusters: []string{
+				"Global-SRE",
+			},
+			ManualSync: true,
+			TimeZone:   "America/Sao_Paulo",
+		}, argov1alpha1.SyncWindow{
+			Kind:       "deny",
+			Schedule:   "15 22 * * 1,5",
+			Duration:   "3h45m",
+			Clusters:   []string{"Global-SRE"},
+			ManualSync: true,
+			TimeZone:   "America/Sao_Paulo",
+		}),
+		ginkgo.Entry("raw schedule", main.MaintenanceWindow{
+			Kind:     "allow",
+			Schedule: "*/30 * * * *",
+			Duration: "10m",
+		}, argov1alpha1.SyncWindow{
+			Kind:         "allow",
+			Schedule:     "*/30 * * * *",
+			Duration:     "10m",
+			Applications: []string{"*"},
+		}),
+	)
+
+	ginkgo.It("only applies windows of the project environment", func() {
+		appProject := generateAppProject(newArgoCDProject(main.ProjectSpec{
+			Environment: "staging",
+			MaintenanceWindows: []main.MaintenanceWindow{
+				main.MaintenanceWindow{
+					Kind: "deny",
+					Days: []string{
+						"weekends",
+					},
+					Environments: []string{
+						"production",
+					},
+				},
+			},
+		}))
+		g.Expect(appProject.Spec.SyncWindows).To(g.BeEmpty())
+	})
+
+	ginkgo.It("keeps windows from the AppProject template", func() {
+		templateWindow := &argov1alpha1.SyncWindow{
+			Kind:         "allow",
+			Schedule:     "0 * * * *",
+			Duration:     "1h",
+			Applications: []string{"*"},
+		}
+
+		appProject := generateAppProject(newArgoCDProject(main.ProjectSpec{
+			Environment: "production",
+			AppProject: argov1alpha1.AppProject{
+				Spec: argov1alpha1.AppProjectSpec{
+					SyncWindows: argov1alpha1.SyncWindows{
+						templateWindow,
+					},
+				},
+			},
+			MaintenanceWindows: []main.MaintenanceWindow{
+				main.MaintenanceWindow{
+					Kind: "deny",
+				},
+			},
+		}))
+		g.Expect(appProject.Spec.SyncWindows).To(g.HaveLen(2))
+		g.Expect(appProject.Spec.SyncWindows[0]).To(g.Equal(templateWindow))
+		g.Expect(appProject.Spec.SyncWindows[1]).To(g.Equal(&argov1alpha1.SyncWindow{
+			Kind:         "deny",
+			Schedule:     "0 0 * * *",
+			Duration:     "24h",
+			Applications: []string{"*"},
+		}))
+	})
+
+	ginkgo.DescribeTable("rejects", func(maintenanceWindow main.MaintenanceWindow, message string) {
+		data, err := yaml.Marshal(newArgoCDProject(main.ProjectSpec{
+			Environment: "production",
+			MaintenanceWindows: []main.MaintenanceWindow{
+				main.MaintenanceWindow{
+					Kind: "allow",
+				},
+				maintenanceWindow,
+			},
+		}))
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		g.Expect(main.GenerateManifests(data, &out)).To(g.MatchError(g.HavePrefix("maintenance window 1: " + message)))
+	},
+		ginkgo.Entry("invalid cron", main.MaintenanceWindow{
+			Kind:     "deny",
+			Schedule: "0 25 * * *",
+			Duration: "1h",
+		}, "cannot parse schedule '0 25 * * *'"),
+		ginkgo.Entry("invalid duration", main.MaintenanceWindow{
+			Kind:     "deny",
+			Schedule: "0 2 * * *",
+			Duration: "1 hour",
+		}, "cannot parse duration '1 hour'"),
+		ginkgo.Entry("schedule without duration", main.MaintenanceWindow{
+			Kind:     "deny",
+			Schedule: "0 2 * * *",
+		}, "schedule requires a duration"),
+		ginkgo.Entry("schedule with days", main.MaintenanceWindow{
+			Kind:     "deny",
+			Schedule: "0 2 * * *",
+			Duration: "1h",
+			Days:     []string{"sun"},
+		}, "schedule cannot be combined with days, start or end"),
+		ginkgo.Entry("invalid time", main.MaintenanceWindow{
+			Kind:  "deny",
+			Start: "2am",
+		}, `invalid time "2am"`),
+		ginkgo.Entry("invalid day", main.MaintenanceWindow{
+			Kind: "deny",
+			Days: []string{"someday"},
+		}, `invalid day "someday"`),
+		ginkgo.Entry("invalid time zone", main.MaintenanceWindow{
+			Kind:     "deny",
+			TimeZone: "Mars/Olympus_Mons",
+		}, `invalid time zone "Mars/Olympus_Mons"`),
+		ginkgo.Entry("invalid kind", main.MaintenanceWindow{
+			Kind: "block",
+		}, "kind 'block' mismatch"),
+	)
+})
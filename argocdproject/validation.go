@@ -0,0 +1,92 @@
+package main
+
+import (
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// validateProjectSpec checks the application and application set templates,
+// and the environment destinations, of spec before anything is generated, so
+// that every broken template is reported at once instead of only surfacing
+// once synced by Argo CD.
+func validateProjectSpec(spec *ProjectSpec) error {
+	specPath := field.NewPath("spec")
+
+	var errs field.ErrorList
+
+	appNames := make(map[string]bool, len(spec.ApplicationTemplates))
+	for i := range spec.ApplicationTemplates {
+		appTemplate := &spec.ApplicationTemplates[i]
+		appPath := specPath.Child("applicationTemplates").Index(i)
+
+		errs = append(errs, validateApplicationName(appPath, appTemplate.Name, appNames)...)
+		errs = append(errs, validateApplicationSpec(appPath.Child("spec"), &appTemplate.Spec)...)
+		errs = append(errs, validateDestination(appPath.Child("spec", "destination"), appTemplate.Spec.Destination)...)
+	}
+
+	appSetNames := make(map[string]bool, len(spec.ApplicationSetTemplates))
+	for i := range spec.ApplicationSetTemplates {
+		appSetTemplate := &spec.ApplicationSetTemplates[i]
+		appSetPath := specPath.Child("applicationSetTemplates").Index(i)
+		appPath := appSetPath.Child("template")
+
+		errs = append(errs, validateApplicationName(appPath, appSetTemplate.Template.Name, appSetNames)...)
+		errs = append(errs, validateApplicationSpec(appPath.Child("spec"), &appSetTemplate.Template.Spec)...)
+		errs = append(errs, validateDestination(appPath.Child("spec", "destination"), appSetTemplate.Template.Spec.Destination)...)
+		for j, destination := range appSetTemplate.Destinations {
+			errs = append(errs, validateDestination(appSetPath.Child("destinations").Index(j).Child("destination"), destination.Destination)...)
+		}
+	}
+
+	for i, environment := range spec.Environments {
+		errs = append(errs, validateDestination(specPath.Child("environments").Index(i).Child("destination"), environment.Destination)...)
+	}
+
+	return errs.ToAggregate()
+}
+
+func validateApplicationName(appPath *field.Path, name string, names map[string]bool) field.ErrorList {
+	namePath := appPath.Child("metadata", "name")
+
+	if name == "" {
+		return field.ErrorList{
+			field.Required(namePath, "application name is required"),
+		}
+	}
+
+	if names[name] {
+		return field.ErrorList{
+			field.Duplicate(namePath, name),
+		}
+	}
+	names[name] = true
+
+	return nil
+}
+
+func validateApplicationSpec(specPath *field.Path, spec *argov1alpha1.ApplicationSpec) field.ErrorList {
+	var errs field.ErrorList
+
+	if spec.HasMultipleSources() {
+		for i, source := range spec.Sources {
+			if source.RepoURL == "" {
+				errs = append(errs, field.Required(specPath.Child("sources").Index(i).Child("repoURL"), "source repository is required"))
+			}
+		}
+	} else if spec.Source == nil {
+		errs = append(errs, field.Required(specPath.Child("source"), "either source or sources is required"))
+	} else if spec.Source.RepoURL == "" {
+		errs = append(errs, field.Required(specPath.Child("source", "repoURL"), "source repository is required"))
+	}
+
+	return errs
+}
+
+func validateDestination(destinationPath *field.Path, destination argov1alpha1.ApplicationDestination) field.ErrorList {
+	if destination.Server != "" && destination.Name != "" {
+		return field.ErrorList{
+			field.Invalid(destinationPath, destination.String(), "server and name cannot both be set"),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package main_test
+
+import (
+	"bytes"
+
+	argov1alpha1 "github.com/argoproj/argo-cd/v2/pkg/apis/application/v1alpha1"
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/argocdproject"
+)
+
+var _ = ginkgo.Describe("ArgoCDProject validation", func() {
+	appTemplate := func(name string, source *argov1alpha1.ApplicationSource, destination argov1alpha1.ApplicationDestination) main.ApplicationTemplate {
+		appTemplate := newApplicationTemplate(name, destination)
+		appTemplate.Spec.Source = source
+		return appTemplate
+	}
+
+	source := &argov1alpha1.ApplicationSource{
+		RepoURL: "https://github.com/t0rr3sp3dr0/github-checker.git",
+	}
+
+	destination := argov1alpha1.ApplicationDestination{
+		Name:      "Global-SRE",
+		Namespace: "github-checker",
+	}
+
+	ambiguousDestination := argov1alpha1.ApplicationDestination{
+		Server:    "https://kubernetes.default.svc",
+		Name:      "in-cluster",
+		Namespace: "github-checker",
+	}
+
+	generate := func(spec main.ProjectSpec) error {
+		data, err := yaml.Marshal(newArgoCDProject(spec))
+		g.Expect(err).To(g.Succeed())
+
+		var out bytes.Buffer
+		return main.GenerateManifests(data, &out)
+	}
+
+	ginkgo.It("accepts valid templates", func() {
+		g.Expect(generate(main.ProjectSpec{
+			ApplicationTemplates: []main.ApplicationTemplate{
+				appTemplate("api", source, destination),
+				appTemplate("worker", source, destination),
+			},
+		})).To(g.Succeed())
+	})
+
+	ginkgo.It("reports every problem at once", func() {
+		err := generate(main.ProjectSpec{
+			ApplicationTemplates: []main.ApplicationTemplate{
+				appTemplate("api", &argov1alpha1.ApplicationSource{}, destination),
+				appTemplate("worker", source, ambiguousDestination),
+				appTemplate("api", source, destination),
+				appTemplate("", source, destination),
+				main.ApplicationTemplate{
+					Application: argov1alpha1.Application{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "dashboard",
+						},
+						Spec: argov1alpha1.ApplicationSpec{
+							Sources: argov1alpha1.ApplicationSources{
+								*source,
+								argov1alpha1.ApplicationSource{
+									Ref: "values",
+								},
+							},
+							Destination: destination,
+						},
+					},
+				},
+				appTemplate("jobs", nil, destination),
+			},
+			ApplicationSetTemplates: []main.ApplicationSetTemplate{
+				main.ApplicationSetTemplate{
+					Template: appTemplate("api", source, destination),
+					Destinations: []main.ApplicationSetDestination{
+						main.ApplicationSetDestination{
+							ID:          "sre",
+							Destination: ambiguousDestination,
+						},
+					},
+				},
+			},
+		})
+		g.Expect(err).To(g.HaveOccurred())
+		g.Expect(err.Error()).To(g.And(
+			g.ContainSubstring("spec.applicationTemplates[0].spec.source.repoURL: Required value"),
+			g.ContainSubstring("spec.applicationTemplates[1].spec.destination: Invalid value"),
+			g.ContainSubstring(`spec.applicationTemplates[2].metadata.name: Duplicate value: "api"`),
+			g.ContainSubstring("spec.applicationTemplates[3].metadata.name: Required value"),
+			g.ContainSubstring("spec.applicationTemplates[4].spec.sources[1].repoURL: Required value"),
+			g.ContainSubstring("spec.applicationTemplates[5].spec.source: Required value"),
+			g.ContainSubstring("spec.applicationSetTemplates[0].destinations[0].destination: Invalid value"),
+		))
+		g.Expect(err.Error()).NotTo(g.ContainSubstring("spec.applicationSetTemplates[0].template.metadata.name"))
+	})
+})
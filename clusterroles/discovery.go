@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	discoveryCacheGroupsFile    = "servergroups.json"
+	discoveryCacheResourcesFile = "serverresources.json"
+)
+
+type ClusterRolesDiscovery struct {
+	Snapshot          string `json:"snapshot,omitempty"`
+	CacheDir          string `json:"cacheDir,omitempty"`
+	KubernetesVersion string `json:"kubernetesVersion,omitempty"`
+}
+
+// discoverResourceLists returns the API resources the ClusterRoles are built
+// from. They are read from the recorded snapshot, the kubectl discovery cache
+// directory, or the built-in resources of the Kubernetes version plus the
+// CustomResourceDefinitions of input when one is set, and from the cluster
+// otherwise.
+func discoverResourceLists(clusterRoles *ClusterRoles, input []byte) ([]*metav1.APIResourceList, error) {
+	snapshot := clusterRoles.Discovery.Snapshot
+	cacheDir := clusterRoles.Discovery.CacheDir
+	kubernetesVersion := clusterRoles.Discovery.KubernetesVersion
+
+	sources := 0
+	for _, source := range []string{snapshot, cacheDir, kubernetesVersion} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return nil, errors.New("only one of discovery snapshot, cacheDir and kubernetesVersion can be set")
+	}
+
+	switch {
+	case snapshot != "":
+		return readDiscoverySnapshot(snapshot)
+	case cacheDir != "":
+		return readDiscoveryCache(cacheDir)
+	case kubernetesVersion != "":
+		return readStreamResourceLists(kubernetesVersion, input)
+	default:
+		return fetchResourceLists(clusterRoles)
+	}
+}
+
+func fetchResourceLists(clusterRoles *ClusterRoles) ([]*metav1.APIResourceList, error) {
+	deferredLoadingClientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(clusterRoles.KubeConfig.LoadingRules, clusterRoles.KubeConfig.Overrides)
+	clientConfig, err := deferredLoadingClientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(clientConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	_, resourceLists, err := discoveryClient.ServerGroupsAndResources()
+	if err != nil {
+		return nil, err
+	}
+
+	return resourceLists, nil
+}
+
+func readDiscoverySnapshot(filePath string) ([]*metav1.APIResourceList, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var resourceLists []*metav1.APIResourceList
+	if err := yaml.Unmarshal(data, &resourceLists); err != nil {
+		return nil, fmt.Errorf("%s: %w", filePath, err)
+	}
+
+	return resourceLists, nil
+}
+
+// readDiscoveryCache reads the API resources cached by kubectl, usually at
+// ~/.kube/cache/discovery/<host>, without checking whether they are stale.
+func readDiscoveryCache(cacheDir string) ([]*metav1.APIResourceList, error) {
+	var groupList metav1.APIGroupList
+	if err := readJSONFile(filepath.Join(cacheDir, discoveryCacheGroupsFile), &groupList); err != nil {
+		return nil, err
+	}
+
+	var resourceLists []*metav1.APIResourceList
+	for _, group := range groupList.Groups {
+		for _, version := range group.Versions {
+			var resourceList metav1.APIResourceList
+			if err := readJSONFile(filepath.Join(cacheDir, filepath.FromSlash(version.GroupVersion), discoveryCacheResourcesFile), &resourceList); err != nil {
+				return nil, err
+			}
+
+			if resourceList.GroupVersion == "" {
+				resourceList.GroupVersion = version.GroupVersion
+			}
+			resourceLists = append(resourceLists, &resourceList)
+		}
+	}
+
+	return resourceLists, nil
+}
+
+func readJSONFile(filePath string, v interface{}) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", filePath, err)
+	}
+	return nil
+}
+
+// recordDiscoverySnapshot writes the API resources of the cluster to the
+// snapshot file of clusterRoles, relative to dir, sorted so that it can be
+// reviewed and diffed in version control.
+func recordDiscoverySnapshot(clusterRoles *ClusterRoles, dir string) error {
+	snapshot := clusterRoles.Discovery.Snapshot
+	if snapshot == "" {
+		return errors.New("discovery snapshot is not set")
+	}
+	if !filepath.IsAbs(snapshot) {
+		snapshot = filepath.Join(dir, snapshot)
+	}
+
+	resourceLists, err := fetchResourceLists(clusterRoles)
+	if err != nil {
+		return err
+	}
+
+	for _, resourceList := range resourceLists {
+		resources := resourceList.APIResources
+		sort.Slice(resources, func(i, j int) bool {
+			return resources[i].Name < resources[j].Name
+		})
+	}
+	sort.Slice(resourceLists, func(i, j int) bool {
+		return resourceLists[i].GroupVersion < resourceLists[j].GroupVersion
+	})
+
+	data, err := yaml.Marshal(resourceLists)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(snapshot, data, 0644)
+}
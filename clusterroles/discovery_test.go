@@ -0,0 +1,192 @@
+package main_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/clusterroles"
+)
+
+var _ = ginkgo.Describe("ClusterRoles discovery", func() {
+	cacheDir := filepath.Join("testdata", "cache")
+
+	clusterRoles := func(discovery main.ClusterRolesDiscovery) main.ClusterRoles {
+		return main.ClusterRoles{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "incognia.com/v1alpha1",
+				Kind:       "ClusterRoles",
+			},
+			Discovery: discovery,
+		}
+	}
+
+	// serveDiscoveryCache serves the kubectl discovery cache fixture as the
+	// discovery endpoints of an API server.
+	serveDiscoveryCache := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer ginkgo.GinkgoRecover()
+
+			var data []byte
+			switch {
+			case r.URL.Path == "/api":
+				data = []byte(`{"kind":"APIVersions","versions":["v1"]}`)
+			case r.URL.Path == "/apis":
+				var groupList metav1.APIGroupList
+				raw, err := os.ReadFile(filepath.Join(cacheDir, "servergroups.json"))
+				g.Expect(err).To(g.Succeed())
+				g.Expect(json.Unmarshal(raw, &groupList)).To(g.Succeed())
+
+				groups := groupList.Groups[:0]
+				for _, group := range groupList.Groups {
+					if group.Name != "" {
+						groups = append(groups, group)
+					}
+				}
+				groupList.Groups = groups
+
+				data, err = json.Marshal(groupList)
+				g.Expect(err).To(g.Succeed())
+			default:
+				groupVersion := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, "/api/"), "/apis/")
+
+				var err error
+				data, err = os.ReadFile(filepath.Join(cacheDir, filepath.FromSlash(groupVersion), "serverresources.json"))
+				if err != nil {
+					http.NotFound(w, r)
+					return
+				}
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(data)
+		}))
+	}
+
+	ginkgo.It("reads a recorded snapshot", func() {
+		output, err := generate(clusterRoles(main.ClusterRolesDiscovery{
+			Snapshot: filepath.Join("testdata", "discovery.yaml"),
+		}), "")
+		g.Expect(err).To(g.Succeed())
+
+		roles := parseClusterRoles(output)
+		g.Expect(ruleForGroup(roles["namespaced-ro"], "")).To(g.Equal(&rbacv1.PolicyRule{
+			APIGroups: []string{""},
+			Resources: []string{"services"},
+			Verbs:     []string{"get", "list", "watch"},
+		}))
+		g.Expect(roles["namespaced-ro"].Rules).To(g.ContainElement(rbacv1.PolicyRule{
+			APIGroups: []string{"batch", "storage.k8s.io"},
+			Resources: []string{"*"},
+			Verbs:     []string{"get", "list", "watch"},
+		}))
+		g.Expect(roles["unnamespaced-ro"].Rules).To(g.Equal([]rbacv1.PolicyRule{
+			rbacv1.PolicyRule{
+				APIGroups: []string{""},
+				Resources: []string{"nodes"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			rbacv1.PolicyRule{
+				APIGroups: []string{"storage.k8s.io"},
+				Resources: []string{"storageclasses"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+		}))
+	})
+
+	ginkgo.It("reads the kubectl discovery cache, including the core group", func() {
+		output, err := generate(clusterRoles(main.ClusterRolesDiscovery{
+			CacheDir: cacheDir,
+		}), "")
+		g.Expect(err).To(g.Succeed())
+
+		roles := parseClusterRoles(output)
+		g.Expect(ruleForGroup(roles["namespaced-ro"], "")).To(g.Equal(&rbacv1.PolicyRule{
+			APIGroups: []string{""},
+			Resources: []string{"configmaps", "pods"},
+			Verbs:     []string{"get", "list", "watch"},
+		}))
+		g.Expect(roles["namespaced-ro"].Rules).To(g.ContainElement(rbacv1.PolicyRule{
+			APIGroups: []string{"apps", "example.com"},
+			Resources: []string{"*"},
+			Verbs:     []string{"get", "list", "watch"},
+		}))
+		g.Expect(roles["unnamespaced-ro"].Rules).To(g.Equal([]rbacv1.PolicyRule{
+			rbacv1.PolicyRule{
+				APIGroups: []string{""},
+				Resources: []string{"namespaces"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+			rbacv1.PolicyRule{
+				APIGroups: []string{"example.com"},
+				Resources: []string{"widgets", "widgets/status"},
+				Verbs:     []string{"get", "list", "watch"},
+			},
+		}))
+	})
+
+	ginkgo.It("records a snapshot in the kustomization directory that builds the same roles", func() {
+		server := serveDiscoveryCache()
+		defer server.Close()
+
+		settings := clusterRoles(main.ClusterRolesDiscovery{
+			Snapshot: "discovery.yaml",
+		})
+		settings.KubeConfig = main.ClusterRolesKubeConfig{
+			LoadingRules: &clientcmd.ClientConfigLoadingRules{},
+			Overrides: &clientcmd.ConfigOverrides{
+				ClusterInfo: clientcmdapi.Cluster{
+					Server: server.URL,
+				},
+			},
+		}
+
+		data, err := yaml.Marshal(settings)
+		g.Expect(err).To(g.Succeed())
+
+		dir := ginkgo.GinkgoT().TempDir()
+		g.Expect(main.RecordDiscoverySnapshot(data, dir)).To(g.Succeed())
+
+		snapshot := filepath.Join(dir, "discovery.yaml")
+		g.Expect(snapshot).To(g.BeARegularFile())
+
+		fromSnapshot, err := generate(clusterRoles(main.ClusterRolesDiscovery{
+			Snapshot: snapshot,
+		}), "")
+		g.Expect(err).To(g.Succeed())
+
+		fromCache, err := generate(clusterRoles(main.ClusterRolesDiscovery{
+			CacheDir: cacheDir,
+		}), "")
+		g.Expect(err).To(g.Succeed())
+
+		g.Expect(fromSnapshot).To(g.Equal(fromCache))
+	})
+
+	ginkgo.It("does not read the resource stream without a Kubernetes version", func() {
+		output, err := generate(clusterRoles(main.ClusterRolesDiscovery{
+			Snapshot: filepath.Join("testdata", "discovery.yaml"),
+		}), "this is not read")
+		g.Expect(err).To(g.Succeed())
+		g.Expect(output).NotTo(g.ContainSubstring("this is not read"))
+	})
+
+	ginkgo.It("rejects several discovery sources", func() {
+		_, err := generate(clusterRoles(main.ClusterRolesDiscovery{
+			Snapshot: filepath.Join("testdata", "discovery.yaml"),
+			CacheDir: cacheDir,
+		}), "")
+		g.Expect(err).To(g.MatchError("only one of discovery snapshot, cacheDir and kubernetesVersion can be set"))
+	})
+})
@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"reflect"
@@ -10,7 +11,6 @@ import (
 
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/tools/clientcmd"
 	"sigs.k8s.io/yaml"
 )
@@ -24,6 +24,8 @@ const (
 	verbList  = "list"
 	verbWatch = "watch"
 
+	recordCommand = "record"
+
 	coreGroupName      = ""
 	secretResourceName = "secrets"
 
@@ -49,6 +51,7 @@ type ClusterRoles struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 	KubeConfig        ClusterRolesKubeConfig `json:"kubeConfig,omitempty"`
+	Discovery         ClusterRolesDiscovery  `json:"discovery,omitempty"`
 }
 
 type ClusterRolesKubeConfig struct {
@@ -57,57 +60,111 @@ type ClusterRolesKubeConfig struct {
 }
 
 func main() {
+	if len(os.Args) == 3 && os.Args[1] == recordCommand {
+		filePath := os.Args[2]
+
+		data, err := os.ReadFile(filePath)
+		if err != nil {
+			log.Panic(filePath, separatorPanic, err)
+		}
+
+		dir, err := os.Getwd()
+		if err != nil {
+			log.Panic(filePath, separatorPanic, err)
+		}
+
+		if err := RecordDiscoverySnapshot(data, dir); err != nil {
+			log.Panic(filePath, separatorPanic, err)
+		}
+		return
+	}
+
 	filePath := os.Args[1]
 
-	loadingRules, overrides, err := readClientConfigSettings(filePath)
+	data, err := os.ReadFile(filePath)
 	if err != nil {
 		log.Panic(filePath, separatorPanic, err)
 	}
 
-	deferredLoadingClientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
-	clientConfig, err := deferredLoadingClientConfig.ClientConfig()
-	if err != nil {
+	if err := GenerateManifests(data, os.Stdin, os.Stdout); err != nil {
 		log.Panic(filePath, separatorPanic, err)
 	}
+}
 
-	discoveryClient, err := discovery.NewDiscoveryClientForConfig(clientConfig)
+// GenerateManifests writes the ClusterRoles built from the settings in data
+// to out. When used as a transformer, Kustomize sends the resources of the
+// kustomization to in, and expects them back along with the generated
+// ClusterRoles, so they are read and echoed when the ClusterRoles are built
+// from them.
+func GenerateManifests(data []byte, in io.Reader, out io.Writer) error {
+	settings, err := unmarshalClusterRoles(data)
 	if err != nil {
-		log.Panic(filePath, separatorPanic, err)
+		return err
+	}
+
+	var input []byte
+	if settings.Discovery.KubernetesVersion != "" {
+		input, err = io.ReadAll(in)
+		if err != nil {
+			return err
+		}
 	}
 
-	index, err := buildIndex(discoveryClient)
+	resourceLists, err := discoverResourceLists(settings, input)
 	if err != nil {
-		log.Panic(filePath, separatorPanic, err)
+		return err
 	}
 
+	index := buildIndex(resourceLists)
+
 	clusterRoles, err := makeClusterRoles(index)
 	if err != nil {
-		log.Panic(filePath, separatorPanic, err)
+		return err
 	}
 	canonicalizeClusterRoles(clusterRoles)
 
+	if len(input) != 0 {
+		if input[len(input)-1] != '\n' {
+			input = append(input, '\n')
+		}
+
+		if _, err := out.Write(append(input, separatorYAML...)); err != nil {
+			return err
+		}
+	}
+
 	for _, clusterRole := range clusterRoles {
 		bytes, err := yaml.Marshal(clusterRole)
 		if err != nil {
-			log.Panic(filePath, separatorPanic, err)
+			return err
 		}
 
-		if _, err := os.Stdout.Write(bytes); err != nil {
-			log.Panic(filePath, separatorPanic, err)
+		if _, err := out.Write(bytes); err != nil {
+			return err
 		}
 
-		if _, err := os.Stdout.Write([]byte(separatorYAML)); err != nil {
-			log.Panic(filePath, separatorPanic, err)
+		if _, err := out.Write([]byte(separatorYAML)); err != nil {
+			return err
 		}
 	}
+
+	return nil
 }
 
-func readClientConfigSettings(filePath string) (*clientcmd.ClientConfigLoadingRules, *clientcmd.ConfigOverrides, error) {
-	data, err := os.ReadFile(filePath)
+// RecordDiscoverySnapshot writes the API resources of the cluster to the
+// discovery snapshot of the settings in data. A relative snapshot path is
+// resolved from dir, which must be the kustomization directory, as Kustomize
+// runs the plugin from it when generating.
+func RecordDiscoverySnapshot(data []byte, dir string) error {
+	settings, err := unmarshalClusterRoles(data)
 	if err != nil {
-		return nil, nil, err
+		return err
 	}
 
+	return recordDiscoverySnapshot(settings, dir)
+}
+
+func unmarshalClusterRoles(data []byte) (*ClusterRoles, error) {
 	clusterRoles := ClusterRoles{
 		KubeConfig: ClusterRolesKubeConfig{
 			LoadingRules: clientcmd.NewDefaultClientConfigLoadingRules(),
@@ -115,22 +172,17 @@ func readClientConfigSettings(filePath string) (*clientcmd.ClientConfigLoadingRu
 		},
 	}
 	if err := yaml.Unmarshal(data, &clusterRoles); err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return clusterRoles.KubeConfig.LoadingRules, clusterRoles.KubeConfig.Overrides, nil
+	return &clusterRoles, nil
 }
 
 type Namespaced bool
 type ResourceIndex map[string]Namespaced
 type GroupIndex map[string]ResourceIndex
 
-func buildIndex(discoveryClient *discovery.DiscoveryClient) (GroupIndex, error) {
-	_, resourceLists, err := discoveryClient.ServerGroupsAndResources()
-	if err != nil {
-		return nil, err
-	}
-
+func buildIndex(resourceLists []*metav1.APIResourceList) GroupIndex {
 	groupIndex := make(GroupIndex)
 	for _, resourceList := range resourceLists {
 		groupVersion := resourceList.GroupVersion
@@ -151,7 +203,7 @@ func buildIndex(discoveryClient *discovery.DiscoveryClient) (GroupIndex, error)
 		}
 	}
 
-	return groupIndex, nil
+	return groupIndex
 }
 
 func makeClusterRoles(index GroupIndex) ([]rbacv1.ClusterRole, error) {
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"reflect"
+	"strings"
+
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilversion "k8s.io/apimachinery/pkg/util/version"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	builtinDir = "builtin"
+
+	separatorSubresource = "/"
+	subresourceStatus    = "status"
+	subresourceScale     = "scale"
+
+	decoderBufferSize = 4096
+)
+
+var (
+	//go:embed builtin
+	builtinFS embed.FS
+
+	customResourceDefinitionKind = reflect.TypeOf(apiextensionsv1.CustomResourceDefinition{}).Name()
+)
+
+// readStreamResourceLists returns the resources a vanilla API server of
+// kubernetesVersion serves, followed by the ones defined by the
+// CustomResourceDefinitions of the Kustomize resource stream in input.
+func readStreamResourceLists(kubernetesVersion string, input []byte) ([]*metav1.APIResourceList, error) {
+	resourceLists, err := readBuiltinResourceLists(kubernetesVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	crdResourceLists, err := readCustomResourceLists(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(resourceLists, crdResourceLists...), nil
+}
+
+func readBuiltinResourceLists(kubernetesVersion string) ([]*metav1.APIResourceList, error) {
+	version, err := utilversion.ParseGeneric(kubernetesVersion)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubernetesVersion %q: %w", kubernetesVersion, err)
+	}
+
+	name := fmt.Sprintf("v%d.%d.yaml", version.Major(), version.Minor())
+	data, err := builtinFS.ReadFile(path.Join(builtinDir, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		versions, err := builtinVersions()
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("unsupported kubernetesVersion %q, must be one of %s", kubernetesVersion, strings.Join(versions, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var resourceLists []*metav1.APIResourceList
+	if err := yaml.Unmarshal(data, &resourceLists); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	return resourceLists, nil
+}
+
+func builtinVersions() ([]string, error) {
+	entries, err := builtinFS.ReadDir(builtinDir)
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		versions = append(versions, strings.TrimSuffix(entry.Name(), path.Ext(entry.Name())))
+	}
+	return versions, nil
+}
+
+// readCustomResourceLists returns the resources, and their status and scale
+// subresources, of every served version of the CustomResourceDefinitions in
+// input, scoped as their spec.scope says.
+func readCustomResourceLists(input []byte) ([]*metav1.APIResourceList, error) {
+	var resourceLists []*metav1.APIResourceList
+
+	decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(input), decoderBufferSize)
+	for {
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+
+		var typeMeta metav1.TypeMeta
+		if err := json.Unmarshal(raw, &typeMeta); err != nil {
+			return nil, err
+		}
+		if typeMeta.Kind != customResourceDefinitionKind || typeMeta.GroupVersionKind().Group != apiextensionsv1.GroupName {
+			continue
+		}
+
+		var crd apiextensionsv1.CustomResourceDefinition
+		if err := json.Unmarshal(raw, &crd); err != nil {
+			return nil, err
+		}
+		if typeMeta.APIVersion != apiextensionsv1.SchemeGroupVersion.String() {
+			return nil, fmt.Errorf("custom resource definition %q: %s is not supported", crd.Name, typeMeta.APIVersion)
+		}
+
+		resourceLists = append(resourceLists, customResourceLists(&crd)...)
+	}
+
+	return resourceLists, nil
+}
+
+func customResourceLists(crd *apiextensionsv1.CustomResourceDefinition) []*metav1.APIResourceList {
+	names := crd.Spec.Names
+	namespaced := crd.Spec.Scope == apiextensionsv1.NamespaceScoped
+
+	var resourceLists []*metav1.APIResourceList
+	for _, version := range crd.Spec.Versions {
+		if !version.Served {
+			continue
+		}
+
+		resources := []metav1.APIResource{
+			metav1.APIResource{
+				Name:         names.Plural,
+				SingularName: names.Singular,
+				Namespaced:   namespaced,
+				Kind:         names.Kind,
+			},
+		}
+		if subresources := version.Subresources; subresources != nil {
+			if subresources.Status != nil {
+				resources = append(resources, metav1.APIResource{
+					Name:       names.Plural + separatorSubresource + subresourceStatus,
+					Namespaced: namespaced,
+					Kind:       names.Kind,
+				})
+			}
+			if subresources.Scale != nil {
+				resources = append(resources, metav1.APIResource{
+					Name:       names.Plural + separatorSubresource + subresourceScale,
+					Namespaced: namespaced,
+					Group:      autoscalingv1.SchemeGroupVersion.Group,
+					Version:    autoscalingv1.SchemeGroupVersion.Version,
+					Kind:       reflect.TypeOf(autoscalingv1.Scale{}).Name(),
+				})
+			}
+		}
+
+		resourceLists = append(resourceLists, &metav1.APIResourceList{
+			GroupVersion: crd.Spec.Group + separatorGV + version.Name,
+			APIResources: resources,
+		})
+	}
+	return resourceLists
+}
@@ -0,0 +1,218 @@
+package main_test
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/onsi/ginkgo/v2"
+	g "github.com/onsi/gomega"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	main "github.com/t0rr3sp3dr0/kustomize-plugins/clusterroles"
+)
+
+var (
+	separatorYaml = regexp.MustCompile("(?m)^---\n")
+
+	clusterRoleGVK = rbacv1.SchemeGroupVersion.WithKind(reflect.TypeOf(rbacv1.ClusterRole{}).Name())
+)
+
+func generate(clusterRoles main.ClusterRoles, input string) (string, error) {
+	data, err := yaml.Marshal(clusterRoles)
+	g.Expect(err).To(g.Succeed())
+
+	var out bytes.Buffer
+	err = main.GenerateManifests(data, strings.NewReader(input), &out)
+	return out.String(), err
+}
+
+func parseClusterRoles(output string) map[string]rbacv1.ClusterRole {
+	clusterRoles := make(map[string]rbacv1.ClusterRole)
+	for _, manifest := range separatorYaml.Split(output, -1) {
+		var meta metav1.TypeMeta
+		g.Expect(yaml.Unmarshal([]byte(manifest), &meta)).To(g.Succeed())
+
+		if meta.GroupVersionKind() == clusterRoleGVK {
+			var clusterRole rbacv1.ClusterRole
+			g.Expect(yaml.Unmarshal([]byte(manifest), &clusterRole)).To(g.Succeed())
+			clusterRoles[clusterRole.Name] = clusterRole
+		}
+	}
+	return clusterRoles
+}
+
+func ruleForGroup(clusterRole rbacv1.ClusterRole, group string) *rbacv1.PolicyRule {
+	for i := range clusterRole.Rules {
+		rule := &clusterRole.Rules[i]
+		if len(rule.APIGroups) == 1 && rule.APIGroups[0] == group {
+			return rule
+		}
+	}
+	return nil
+}
+
+var _ = ginkgo.Describe("ClusterRoles from the resource stream", func() {
+	clusterRoles := func(kubernetesVersion string) main.ClusterRoles {
+		return main.ClusterRoles{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "incognia.com/v1alpha1",
+				Kind:       "ClusterRoles",
+			},
+			Discovery: main.ClusterRolesDiscovery{
+				KubernetesVersion: kubernetesVersion,
+			},
+		}
+	}
+
+	crd := func(apiVersion string, group string, plural string, scope string, versions string) string {
+		return `apiVersion: ` + apiVersion + `
+kind: CustomResourceDefinition
+metadata:
+  name: ` + plural + `.` + group + `
+spec:
+  group: ` + group + `
+  names:
+    kind: Widget
+    plural: ` + plural + `
+    singular: widget
+  scope: ` + scope + `
+  versions:
+` + versions
+	}
+
+	ginkgo.It("builds the roles from the bundled built-in resources", func() {
+		output, err := generate(clusterRoles("v1.24.3"), "")
+		g.Expect(err).To(g.Succeed())
+
+		roles := parseClusterRoles(output)
+		g.Expect(roles).To(g.HaveLen(4))
+
+		coreRule := ruleForGroup(roles["namespaced-ro"], "")
+		g.Expect(coreRule).NotTo(g.BeNil())
+		g.Expect(coreRule.Resources).To(g.ContainElements("pods", "configmaps", "services"))
+		g.Expect(coreRule.Resources).NotTo(g.ContainElements("secrets", "nodes"))
+
+		unnamespacedCoreRule := ruleForGroup(roles["unnamespaced-ro"], "")
+		g.Expect(unnamespacedCoreRule).NotTo(g.BeNil())
+		g.Expect(unnamespacedCoreRule.Resources).To(g.ContainElements("namespaces", "nodes", "persistentvolumes"))
+		g.Expect(unnamespacedCoreRule.Resources).NotTo(g.ContainElement("pods"))
+	})
+
+	ginkgo.It("rejects unknown Kubernetes versions", func() {
+		_, err := generate(clusterRoles("v1.12"), "")
+		g.Expect(err).To(g.MatchError(`unsupported kubernetesVersion "v1.12", must be one of v1.24`))
+	})
+
+	ginkgo.It("scopes custom resources as their definitions say", func() {
+		input := crd("apiextensions.k8s.io/v1", "example.com", "widgets", "Cluster", `  - name: v1
+    served: true
+    storage: true
+`) + "---\n" + crd("apiextensions.k8s.io/v1", "gadgets.example.com", "gadgets", "Namespaced", `  - name: v1
+    served: true
+    storage: true
+`)
+
+		output, err := generate(clusterRoles("v1.24"), input)
+		g.Expect(err).To(g.Succeed())
+
+		roles := parseClusterRoles(output)
+		g.Expect(ruleForGroup(roles["unnamespaced-ro"], "example.com")).To(g.Equal(&rbacv1.PolicyRule{
+			APIGroups: []string{"example.com"},
+			Resources: []string{"widgets"},
+			Verbs:     []string{"get", "list", "watch"},
+		}))
+		g.Expect(ruleForGroup(roles["unnamespaced-rw"], "example.com")).To(g.Equal(&rbacv1.PolicyRule{
+			APIGroups: []string{"example.com"},
+			Resources: []string{"widgets"},
+			Verbs:     []string{"*"},
+		}))
+		g.Expect(ruleForGroup(roles["unnamespaced-ro"], "gadgets.example.com")).To(g.BeNil())
+
+		g.Expect(roles["namespaced-ro"].Rules).To(g.ContainElement(g.HaveField("APIGroups", g.ContainElements("example.com", "gadgets.example.com"))))
+	})
+
+	ginkgo.It("only includes served versions", func() {
+		input := crd("apiextensions.k8s.io/v1", "example.com", "widgets", "Cluster", `  - name: v1alpha1
+    served: false
+    storage: true
+`)
+
+		output, err := generate(clusterRoles("v1.24"), input)
+		g.Expect(err).To(g.Succeed())
+
+		roles := parseClusterRoles(output)
+		g.Expect(ruleForGroup(roles["unnamespaced-ro"], "example.com")).To(g.BeNil())
+		g.Expect(roles["namespaced-ro"].Rules).NotTo(g.ContainElement(g.HaveField("APIGroups", g.ContainElement("example.com"))))
+	})
+
+	ginkgo.It("includes the status and scale subresources", func() {
+		input := crd("apiextensions.k8s.io/v1", "example.com", "widgets", "Cluster", `  - name: v1alpha1
+    served: false
+    storage: false
+  - name: v1
+    served: true
+    storage: true
+    subresources:
+      status: {}
+      scale:
+        specReplicasPath: .spec.replicas
+        statusReplicasPath: .status.replicas
+`)
+
+		output, err := generate(clusterRoles("v1.24"), input)
+		g.Expect(err).To(g.Succeed())
+
+		roles := parseClusterRoles(output)
+		rule := ruleForGroup(roles["unnamespaced-ro"], "example.com")
+		g.Expect(rule).NotTo(g.BeNil())
+		g.Expect(rule.Resources).To(g.Equal([]string{"widgets", "widgets/scale", "widgets/status"}))
+	})
+
+	ginkgo.It("skips documents that are not custom resource definitions", func() {
+		input := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: widgets
+---
+apiVersion: example.com/v1
+kind: CustomResourceDefinition
+metadata:
+  name: widgets.example.com
+spec:
+  group: example.com
+`
+
+		output, err := generate(clusterRoles("v1.24"), input)
+		g.Expect(err).To(g.Succeed())
+
+		roles := parseClusterRoles(output)
+		g.Expect(roles).To(g.HaveLen(4))
+		g.Expect(ruleForGroup(roles["unnamespaced-ro"], "example.com")).To(g.BeNil())
+	})
+
+	ginkgo.It("rejects v1beta1 custom resource definitions", func() {
+		input := crd("apiextensions.k8s.io/v1beta1", "example.com", "widgets", "Cluster", `  - name: v1
+    served: true
+    storage: true
+`)
+
+		_, err := generate(clusterRoles("v1.24"), input)
+		g.Expect(err).To(g.MatchError(`custom resource definition "widgets.example.com": apiextensions.k8s.io/v1beta1 is not supported`))
+	})
+
+	ginkgo.It("echoes the resource stream before the roles", func() {
+		input := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: widgets`
+
+		output, err := generate(clusterRoles("v1.24"), input)
+		g.Expect(err).To(g.Succeed())
+		g.Expect(output).To(g.HavePrefix(input + "\n---\n"))
+		g.Expect(parseClusterRoles(output)).To(g.HaveLen(4))
+	})
+})